@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package clireport holds what the command line tools share to report on a
+// run: exit codes, usage errors and the end of run summary
+
+package clireport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+)
+
+//Exit codes
+const (
+	EXIT_OK     = 0
+	EXIT_FAILED = 1 //some paths could not be processed
+	EXIT_USAGE  = 2
+)
+
+//Prints the usage of cmd and msg, and exits with EXIT_USAGE
+func UsageError(cmd *cobra.Command, msg string) {
+	cmd.Usage()
+	log.Print(msg)
+	os.Exit(EXIT_USAGE)
+}
+
+//A path that failed, as listed in the summary. Path is empty for failures
+//of the run as a whole, e.g. a bad spec
+type PathError struct {
+	Path  string `json:"path,omitempty"`
+	Error string `json:"error"`
+}
+
+//Failed paths, written as an empty list rather than null when there are none
+type PathErrors []PathError
+
+func (e PathErrors) MarshalJSON() ([]byte, error) {
+	if e == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]PathError(e))
+}
+
+//End of run counts every tool reports. Tools embed it in their own summary,
+//adding their counts, and guard it with their own lock
+type Summary struct {
+	Scanned            int64      `json:"scanned"` //paths whose ACL was read
+	SkippedUnsupported int64      `json:"skipped_unsupported"`
+	Errors             PathErrors `json:"errors"`
+
+	exitCode int
+}
+
+//An error Fail already listed, so Abort doesn't list it twice
+type listedError struct {
+	error
+}
+
+func (e listedError) Unwrap() error {
+	return e.error
+}
+
+//Lists the failure of path. Returns err, marked as listed, for the caller to
+//hand back when the failure ends the run
+func (s *Summary) Fail(path string, err error) error {
+	s.Errors = append(s.Errors, PathError{Path: path, Error: err.Error()})
+	return listedError{err}
+}
+
+//Ends the run early because of err, which is logged and, unless Fail already
+//did, listed under path. The run then exits with code at least
+func (s *Summary) Abort(path string, err error, code int) {
+	log.Print(err)
+	var listed listedError
+	if !errors.As(err, &listed) {
+		s.Errors = append(s.Errors, PathError{Path: path, Error: err.Error()})
+	}
+	s.exitCode = max(s.exitCode, code)
+}
+
+//Exit code for the run so far
+func (s *Summary) ExitCode() int {
+	if len(s.Errors) > 0 {
+		return max(s.exitCode, EXIT_FAILED)
+	}
+	return s.exitCode
+}
+
+//Prints counts, the tool's own "name: n" list, to stderr, followed by the
+//failed paths
+func (s *Summary) Print(counts string) {
+	fmt.Fprintln(os.Stderr, counts)
+	for _, failure := range s.Errors {
+		if failure.Path == "" {
+			fmt.Fprintf(os.Stderr, "  %s\n", failure.Error)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", failure.Path, failure.Error)
+	}
+}
+
+//Writes summary, a tool's summary embedding Summary, as JSON to file, "-"
+//meaning stdout
+func WriteJSON(file string, summary interface{}) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if file == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
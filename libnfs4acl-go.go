@@ -1,55 +1,50 @@
 // Copyright (c) 2017 Cory Close. See LICENSE file.
 
 // Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+//
+// The functions wrap github.com/cclose/libnfs4acl-go/v2, and accept and write
+// less than the original code did:
+//
+//   - ACLs are checked before packing or setting: empty whos, whos that
+//     aren't valid UTF-8, hold NUL bytes or exceed v2's NFS4_MAX_WHO_LENGTH,
+//     and ACLs over v2's Limits are refused where they used to be written
+//   - loading refuses buffers with more ACEs than they can hold, or more
+//     than v2's Limits allow
+//   - the OWNER@, GROUP@ and EVERYONE@ pseudo flags are no longer packed.
+//     They used to go over the wire as 0x80, 0x100 and 0x200, which NFSv4.1
+//     servers read as INHERITED_ACE and undefined flags
+//
+// Deprecated: this is the original API, kept as thin wrappers so existing
+// callers still build. New code should import
+// github.com/cclose/libnfs4acl-go/v2, which has Go style names, distinct
+// types for ACE types, flags and masks, and everything added since
 
 package nfs4acl
 
 import (
-	//"fmt"
-	"golang.org/x/sys/unix"
+	v2 "github.com/cclose/libnfs4acl-go/v2"
 	"os"
-	//"unsafe"
 )
 
 const NFS4_ACL_XATTR = "system.nfs4_acl"
 const ERROR_NFS4_NOT_SUPPORTED = "operation not supported"
 const XATTR_REPLACE_FLAG = 0x2
 
+//Deprecated: use GetACL from v2
 func Nfs4_getacl_for_path(path string) (acl *NFS4ACL, err error) {
-	//Validate the Path and detect directory
-	fi, err := os.Stat(path)
-	if err != nil {
-		//File Not Exists and other errors
-		return
-	} //implicit else
-	isDir := fi.IsDir() //detect if the path is a directory
-
-	return GetAcl(path, isDir)
+	return wrapACL(v2.GetACL(path))
 }
 
 //Proxy function that can be used when you already know your path exists and
 //if the path is a directory or not. this is helpful when using filepath walks
+//
+//Deprecated: use LoadACL from v2
 func GetAcl(path string, isDir bool) (acl *NFS4ACL, err error) {
-	//get the size of our value buffer
-	var result int
-	result, err = nfs4_getxattr(path, nil)
-	if err != nil {
-		return
-	}
-
-	xattr := make([]byte, result, result)
-	result, err = nfs4_getxattr(path, xattr)
-	if err != nil {
-		return
-	}
-
-	acl, err = XAttrLoad(xattr[:result], isDir)
-
-	//return acl, err
-	return
-
+	return wrapACL(v2.LoadACL(path, isDir))
 }
 
+//Deprecated: use SetACL from v2, which reports a missing path through the
+//setxattr error instead of a stat
 func Nfs4_setacl_for_path(path string, acl *NFS4ACL) (err error) {
 	//Validate the Path and detect directory
 	_, err = os.Stat(path)
@@ -61,22 +56,9 @@ func Nfs4_setacl_for_path(path string, acl *NFS4ACL) (err error) {
 	return SetACL(path, acl)
 }
 
+//The ACL is checked as by PackXAttr first
+//
+//Deprecated: use SetACL from v2
 func SetACL(path string, acl *NFS4ACL) (err error) {
-	err = nfs4_setxattr(path, acl)
-
-	return
-}
-
-func nfs4_getxattr(path string, value []byte) (int, error) {
-	result, err := unix.Getxattr(path, NFS4_ACL_XATTR, value)
-	//check result and err for know problems
-
-	return result, err
-}
-
-func nfs4_setxattr(path string, acl *NFS4ACL) error {
-	xattr, err := acl.PackXAttr()
-	err = unix.Setxattr(path, NFS4_ACL_XATTR, xattr, XATTR_REPLACE_FLAG)
-
-	return err
+	return v2.SetACL(path, acl.unwrap())
 }
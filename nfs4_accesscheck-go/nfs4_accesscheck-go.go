@@ -0,0 +1,145 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"log"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+const (
+	EXIT_GRANTED = 0
+	EXIT_DENIED  = 1
+	EXIT_TROUBLE = 2
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: %s -u user [-g group,...] -p perms path\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	userName := flag.String("u", "", "`user` to check, with or without the NFSv4 domain")
+	groupList := flag.String("g", "", "comma separated `groups` of the user (default: looked up locally)")
+	perms := flag.String("p", "", "requested access as permission letters, e.g. `rw`")
+	flag.Usage = usage
+
+	flag.Parse()
+	if *userName == "" || *perms == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(EXIT_TROUBLE)
+	}
+	path := flag.Arg(0)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Print(err)
+		os.Exit(EXIT_TROUBLE)
+	}
+	acl, err := nfs4acl.LoadACL(path, info.IsDir())
+	if err != nil {
+		log.Print(err)
+		os.Exit(EXIT_TROUBLE)
+	}
+
+	//reuse the spec parser for the permission letters
+	request, err := nfs4acl.ParseACE("A::EVERYONE@:"+*perms, info.IsDir())
+	if err != nil {
+		log.Print(err)
+		os.Exit(EXIT_TROUBLE)
+	}
+
+	principal := lookupPrincipal(*userName, *groupList)
+	if uid, gid, ok := nfs4acl.OwnerIDs(info); ok {
+		principal.FileUID = uid
+		principal.FileGID = gid
+	}
+
+	granted, deciding, err := nfs4acl.CheckAccess(acl, principal, request.AccessMask)
+	switch {
+	case err != nil:
+		log.Print(err)
+		os.Exit(EXIT_TROUBLE)
+	case granted:
+		fmt.Printf("granted by %s\n", deciding.Format(false, info.IsDir()))
+		os.Exit(EXIT_GRANTED)
+	case deciding != nil:
+		fmt.Printf("denied by %s\n", deciding.Format(false, info.IsDir()))
+	default:
+		fmt.Println("denied: no ACE grants all requested access")
+	}
+	os.Exit(EXIT_DENIED)
+}
+
+//Resolves the user, and its groups unless given, through the library's
+//principal resolver, so a resolver set up for the library applies here too
+func lookupPrincipal(name, groupList string) nfs4acl.Principal {
+	resolver := nfs4acl.GetPrincipalResolver()
+	principal := nfs4acl.Principal{
+		Name: name,
+		//never matches OWNER@ unless the user resolves
+		UID: ^uint32(0),
+	}
+	//whos of other domains are never taken for local accounts
+	if mapper, err := nfs4acl.NewIDMapper(); err == nil {
+		principal.Domain = mapper.Domain
+	}
+
+	if uid, err := resolver.LookupUser(bareName(name)); err == nil {
+		principal.UID = uid
+		if groupList == "" {
+			principal.GIDs, principal.Groups = userGroups(uid, resolver)
+		}
+	}
+
+	if groupList != "" {
+		for _, name := range strings.Split(groupList, ",") {
+			principal.Groups = append(principal.Groups, name)
+			if gid, err := resolver.LookupGroup(bareName(name)); err == nil {
+				principal.GIDs = append(principal.GIDs, gid)
+			}
+		}
+	}
+
+	return principal
+}
+
+//Gids and names of the groups uid belongs to. A PrincipalResolver can't list
+//them, so the list comes from the local NSS, and users it doesn't know need -g
+func userGroups(uid uint32, resolver nfs4acl.PrincipalResolver) (gids []uint32, names []string) {
+	usr, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return nil, nil
+	}
+	ids, _ := usr.GroupIds()
+	for _, id := range ids {
+		gid := parseID(id)
+		gids = append(gids, gid)
+		if name, err := resolver.GroupName(gid); err == nil {
+			names = append(names, name)
+		}
+	}
+
+	return gids, names
+}
+
+func parseID(id string) uint32 {
+	n, _ := strconv.ParseUint(id, 10, 32)
+	return uint32(n)
+}
+
+func bareName(name string) string {
+	if at := strings.LastIndex(name, "@"); at >= 0 {
+		return name[:at]
+	}
+	return name
+}
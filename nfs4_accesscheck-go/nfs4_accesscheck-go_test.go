@@ -0,0 +1,44 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"testing"
+)
+
+//Resolves the names in the map, nothing else
+type fakeResolver map[string]uint32
+
+func (r fakeResolver) LookupUser(name string) (uint32, error) {
+	if id, ok := r[name]; ok {
+		return id, nil
+	}
+	return 0, errors.New("unknown " + name)
+}
+
+func (r fakeResolver) LookupGroup(name string) (uint32, error) { return r.LookupUser(name) }
+func (r fakeResolver) UserName(uid uint32) (string, error)     { return "", errors.New("unused") }
+func (r fakeResolver) GroupName(gid uint32) (string, error)    { return "", errors.New("unused") }
+
+func TestLookupPrincipalUsesResolver(t *testing.T) {
+	nfs4acl.SetPrincipalResolver(fakeResolver{"nfsbob": 4242, "nfsstaff": 4343})
+	t.Cleanup(func() { nfs4acl.SetPrincipalResolver(nil) })
+
+	principal := lookupPrincipal("nfsbob@example.com", "nfsstaff@example.com,nobodyknows")
+	if principal.UID != 4242 {
+		t.Errorf("uid %d, want 4242", principal.UID)
+	}
+	if len(principal.GIDs) != 1 || principal.GIDs[0] != 4343 {
+		t.Errorf("gids %v, want [4343]", principal.GIDs)
+	}
+	if len(principal.Groups) != 2 {
+		t.Errorf("groups %q, want both given", principal.Groups)
+	}
+
+	unknown := lookupPrincipal("nfsalice", "")
+	if unknown.UID != ^uint32(0) || len(unknown.GIDs) != 0 {
+		t.Errorf("unresolved user got uid %d and gids %v", unknown.UID, unknown.GIDs)
+	}
+}
@@ -5,11 +5,9 @@
 package nfs4acl
 
 import (
-	"encoding/binary"
 	"errors"
+	v2 "github.com/cclose/libnfs4acl-go/v2"
 	"unsafe"
-	//"bytes"
-	//"fmt"
 )
 
 //Size of xattr packing atoms (uint32) in bytes
@@ -126,372 +124,149 @@ const (
 	//PERM_GENERIC_EXECUTE = 'X'
 )
 
+//Deprecated: use ACL from v2
 type NFS4ACL struct {
-	isDirectory bool
-	aceList     []*NFS4ACE
+	acl *v2.ACL
 }
 
-func XAttrLoad(value []byte, isDir bool) (newACL *NFS4ACL, err error) {
-	newACL = &NFS4ACL{
-		isDirectory: isDir,
-	}
-
-	//This could probably be a constant '4' but i feel safer measuring
-	curAtom := int(0)
-	maxAtom := len(value)
-	if maxAtom < ATOM_SIZE {
-		err = errors.New("invalid input buffer 'value'")
-		return
+func wrapACL(acl *v2.ACL, err error) (*NFS4ACL, error) {
+	if err != nil {
+		return nil, err
 	}
+	return &NFS4ACL{acl: acl}, nil
+}
 
-	//value is an array of bytes
-	//the ACL data is stored as 32bit ints in this array
-	//we read this data by stepping 1 32bit at a time through the array
-	//ACL Packing structure:
-	// [numAces]{ACE}{ACE}{ACE}
-
-	//We make sure we convert FROM network byte order as a uint32
-	numAces := int(binary.BigEndian.Uint32(value[curAtom:]))
-
-	//increment our pointer to the next uint32
-	curAtom += ATOM_SIZE
-
-	for curAce := 0; curAce < numAces; curAce++ {
-		//sanity check our boundaries
-		if curAtom >= maxAtom {
-			err = errors.New("buffer overflow")
-			return
-		}
-
-		//ACE Packing structure:
-		// [type][flag][AccessMask][who_Len][who_str]{whoLen}
-
-		//verify there's room in the buffer for the next 4 uint32s
-		if (curAtom + (ATOM_SIZE * 4)) >= maxAtom {
-			err = errors.New("buffer overflow")
-			return
-		}
-
-		//retrieve type
-		aceType := binary.BigEndian.Uint32(value[curAtom:])
-		curAtom += ATOM_SIZE //increment ptr
-
-		//retrieve flag
-		aceFlag := binary.BigEndian.Uint32(value[curAtom:])
-		curAtom += ATOM_SIZE //increment ptr
-
-		//retrieve access mask
-		aceMask := binary.BigEndian.Uint32(value[curAtom:])
-		curAtom += ATOM_SIZE //increment ptr
-
-		//get the size, in bytes, of the Who string
-		whoLen := int(binary.BigEndian.Uint32(value[curAtom:]))
-		curAtom += ATOM_SIZE //increment ptr
-
-		//retrieve the Who string
-		aceWho := string(value[curAtom:(whoLen + curAtom)])
-		//and increment the pointer
-		curAtom += AceWhoStringAtomLength(whoLen)
-
-		//create a new ACE struct and append it to our ACL struct
-		newACE := NewNFS4ACE(aceType, aceFlag, aceMask, aceWho)
-		newACL.aceList = append(newACL.aceList, newACE)
+//The wrapped ACL. A zero NFS4ACL is an empty file ACL, as it always was
+func (acl *NFS4ACL) unwrap() *v2.ACL {
+	if acl.acl == nil {
+		acl.acl = v2.NewACL(false)
 	}
+	return acl.acl
+}
 
-	return //returns newACL, err
+//Refuses more than the original did, see the package documentation
+//
+//Deprecated: use XAttrLoad from v2
+func XAttrLoad(value []byte, isDir bool) (newACL *NFS4ACL, err error) {
+	return wrapACL(v2.XAttrLoad(value, isDir))
 }
 
-//We reset our slice... this won't garbage collect the old aces, but that's ok because the ACLs are short lived anyways
 func (acl *NFS4ACL) ClearACEs() error {
-	acl.aceList = acl.aceList[:0]
-    return nil
+	return acl.unwrap().ClearACEs()
 }
 
 func (acl *NFS4ACL) AddACE(aceType, aceFlags, aceMask uint32, aceWho string) {
-	acl.aceList = append(acl.aceList, NewNFS4ACE(aceType, aceFlags, aceMask, aceWho))
+	acl.unwrap().AddACE(v2.AceType(aceType), v2Flags(aceFlags), v2.AccessMask(aceMask), aceWho)
 }
 
 func (acl *NFS4ACL) PrintACL(verbose bool) error {
-	for _, ace := range acl.aceList {
-		ace.PrintACE(verbose, acl.isDirectory)
-	}
-	return nil
+	return acl.unwrap().PrintACL(verbose)
 }
 
 func (acl *NFS4ACL) XAttrSize() (xAttrSize int) {
-	//ACL Packing structure:
-	// [num_aces]{ACE}{ACE}{ACE}
-	//ACE Counter, 1 atom to count the # of aces
-	xAttrSize = ATOM_SIZE
-
-	//ACE Packing structure:
-	// [type][flag][AccessMask][who_Len][who_str]{who_len}
-	for _, ace := range acl.aceList {
-		//each ACE has 4 atom's to store type, flag, access mask and wholen
-		xAttrSize += ATOM_SIZE * 4
-		//and add space for the whostring
-		xAttrSize += AceWhoStringAtomLength(len(ace.Who))
-	}
-
-	//and that's all
-	return
+	return acl.unwrap().XAttrSize()
 }
 
+//Checks the whos and the Limits, and leaves out the pseudo flags, see the
+//package documentation
 func (acl *NFS4ACL) PackXAttr() (xattr []byte, err error) {
-	err = nil
-	aclSize := acl.XAttrSize()
-	xattr = make([]byte, aclSize, aclSize)
-	currAtom := int(0)
-
-	//ACL Packing structure:
-	// [num_aces]{ACE}{ACE}{ACE}
-	// pack number of aces as a uint32 into the buffer
-	// use BigEndian for Network Byte order
-	binary.BigEndian.PutUint32(xattr[currAtom:], uint32(len(acl.aceList)))
-	currAtom += ATOM_SIZE
-
-	//ACE Packing structure:
-	// [type][flag][AccessMask][who_Len][who_str]{who_len}
-	for _, ace := range acl.aceList {
-		//write ace type
-		binary.BigEndian.PutUint32(xattr[currAtom:], ace.AceType)
-		currAtom += ATOM_SIZE
-		//write ace Flags
-		binary.BigEndian.PutUint32(xattr[currAtom:], ace.Flags)
-		currAtom += ATOM_SIZE
-		//write ace access mask
-		binary.BigEndian.PutUint32(xattr[currAtom:], ace.AccessMask)
-		currAtom += ATOM_SIZE
-		//write ace whoLen
-		whoLen := len(ace.Who)
-		binary.BigEndian.PutUint32(xattr[currAtom:], uint32(whoLen))
-		currAtom += ATOM_SIZE
-
-		//Write the Who string into the data
-		copy(xattr[currAtom:], ace.Who)
-		currAtom += AceWhoStringAtomLength(whoLen)
-	}
+	return acl.unwrap().PackXAttr()
+}
 
-	return
+//v2 knows more who types, which this package never did
+func checkWhoType(whoType uint) error {
+	if whoType > NFS4_ACL_WHO_EVERYONE {
+		return errors.New("unsupported who type")
+	}
+	return nil
 }
 
 func (acl *NFS4ACL) ApplyAccessMask(accessMask uint32) {
-	for _, ace := range acl.aceList {
-		ace.applyAccessMask(accessMask)
-	}
+	acl.unwrap().ApplyAccessMask(v2.AccessMask(accessMask))
 }
 
-// Similar to applyAccessMaskByWho, but the whoType matching is faster if usable
 func (acl *NFS4ACL) ApplyAccessMaskByWhoType(accessMask uint32, whoType uint) error {
-	if whoType == NFS4_ACL_WHO_NAMED { return errors.New("named who not allowed")
-	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_EVERYONE {
-		return errors.New("unsupported who type")
+	if err := checkWhoType(whoType); err != nil {
+		return err
 	}
-
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only apply if the whotype matches
-		if ace.WhoType == whoType {
-			ace.applyAccessMask(accessMask)
-		}
-	}
-
-	return nil
+	return acl.unwrap().ApplyAccessMaskByWhoType(v2.AccessMask(accessMask), whoType)
 }
 
 func (acl *NFS4ACL) ApplyAccessMaskByWho(accessMask uint32, who string) error {
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only apply if the who matches
-		if ace.Who == who {
-			ace.applyAccessMask(accessMask)
-		}
-	}
-
-	return nil
+	return acl.unwrap().ApplyAccessMaskByWho(v2.AccessMask(accessMask), who)
 }
 
 func (acl *NFS4ACL) RemoveAccessMask(accessMask uint32) {
-	for _, ace := range acl.aceList {
-		ace.removeAccessMask(accessMask)
-	}
+	acl.unwrap().RemoveAccessMask(v2.AccessMask(accessMask))
 }
 
-// Similar to removeAccessMaskByWho, but the whoType matching is faster if usable
 func (acl *NFS4ACL) RemoveAccessMaskByWhoType(accessMask uint32, whoType uint) error {
-	if whoType == NFS4_ACL_WHO_NAMED {
-		return errors.New("named who not allowed")
-	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_EVERYONE {
-		return errors.New("unsupported who type")
+	if err := checkWhoType(whoType); err != nil {
+		return err
 	}
-
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only remove if the whotype matches
-		if ace.WhoType == whoType {
-			ace.removeAccessMask(accessMask)
-		}
-	}
-
-	return nil
+	return acl.unwrap().RemoveAccessMaskByWhoType(v2.AccessMask(accessMask), whoType)
 }
 
 func (acl *NFS4ACL) RemoveAccessMaskByWho(accessMask uint32, who string) error {
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only remove if the who matches
-		if ace.Who == who {
-			ace.removeAccessMask(accessMask)
-		}
-	}
-
-	return nil
+	return acl.unwrap().RemoveAccessMaskByWho(v2.AccessMask(accessMask), who)
 }
 
 func (acl *NFS4ACL) SetAccessMask(accessMask uint32) {
-	for _, ace := range acl.aceList {
-		ace.setAccessMask(accessMask)
-	}
+	acl.unwrap().SetAccessMask(v2.AccessMask(accessMask))
 }
 
-// Similar to setAccessMaskByWho, but the whoType matching is faster if usable
 func (acl *NFS4ACL) SetAccessMaskByWhoType(accessMask uint32, whoType uint) error {
-	if whoType == NFS4_ACL_WHO_NAMED {
-		return errors.New("named who not allowed")
-	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_EVERYONE {
-		return errors.New("unsupported who type")
-	}
-
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only set if the whotype matches
-		if ace.WhoType == whoType {
-			ace.setAccessMask(accessMask)
-		}
+	if err := checkWhoType(whoType); err != nil {
+		return err
 	}
-
-	return nil
+	return acl.unwrap().SetAccessMaskByWhoType(v2.AccessMask(accessMask), whoType)
 }
 
 func (acl *NFS4ACL) SetAccessMaskByWho(accessMask uint32, who string) error {
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only set if the who matches
-		if ace.Who == who {
-			ace.setAccessMask(accessMask)
-		}
-	}
-
-	return nil
+	return acl.unwrap().SetAccessMaskByWho(v2.AccessMask(accessMask), who)
 }
 
 func (acl *NFS4ACL) ApplyFlags(flags uint32) {
-	for _, ace := range acl.aceList {
-		ace.applyFlags(flags)
-	}
+	acl.unwrap().ApplyFlags(v2Flags(flags))
 }
 
-// Similar to applyFlagsByWho, but the whoType matching is faster if usable
 func (acl *NFS4ACL) ApplyFlagsByWhoType(flags uint32, whoType uint) error {
-	if whoType == NFS4_ACL_WHO_NAMED {
-		return errors.New("named who not allowed")
-	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_EVERYONE {
-		return errors.New("unsupported who type")
-	}
-
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only apply if the whotype matches
-		if ace.WhoType == whoType {
-			ace.applyFlags(flags)
-		}
+	if err := checkWhoType(whoType); err != nil {
+		return err
 	}
-
-	return nil
+	return acl.unwrap().ApplyFlagsByWhoType(v2Flags(flags), whoType)
 }
 
 func (acl *NFS4ACL) ApplyFlagsByWho(flags uint32, who string) error {
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only apply if the who matches
-		if ace.Who == who {
-			ace.applyFlags(flags)
-		}
-	}
-
-	return nil
+	return acl.unwrap().ApplyFlagsByWho(v2Flags(flags), who)
 }
 
 func (acl *NFS4ACL) RemoveFlags(flags uint32) {
-	for _, ace := range acl.aceList {
-		ace.removeFlags(flags)
-	}
+	acl.unwrap().RemoveFlags(v2Flags(flags))
 }
 
-// Similar to removeFlagsByWho, but the whoType matching is faster if usable
 func (acl *NFS4ACL) RemoveFlagsByWhoType(flags uint32, whoType uint) error {
-	if whoType == NFS4_ACL_WHO_NAMED {
-		return errors.New("named who not allowed")
-	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_EVERYONE {
-		return errors.New("unsupported who type")
-	}
-
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only remove if the whotype matches
-		if ace.WhoType == whoType {
-			ace.removeFlags(flags)
-		}
+	if err := checkWhoType(whoType); err != nil {
+		return err
 	}
-
-	return nil
+	return acl.unwrap().RemoveFlagsByWhoType(v2Flags(flags), whoType)
 }
 
 func (acl *NFS4ACL) RemoveFlagsByWho(flags uint32, who string) error {
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only remove if the who matches
-		if ace.Who == who {
-			ace.removeFlags(flags)
-		}
-	}
-
-	return nil
+	return acl.unwrap().RemoveFlagsByWho(v2Flags(flags), who)
 }
 
 func (acl *NFS4ACL) SetFlags(flags uint32) {
-	for _, ace := range acl.aceList {
-		ace.setFlags(flags)
-	}
+	acl.unwrap().SetFlags(v2Flags(flags))
 }
 
-// Similar to setFlagsByWho, but the whoType matching is faster if usable
 func (acl *NFS4ACL) SetFlagsByWhoType(flags uint32, whoType uint) error {
-	if whoType == NFS4_ACL_WHO_NAMED {
-		return errors.New("named who not allowed")
-	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_EVERYONE {
-		return errors.New("unsupported who type")
-	}
-
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only set if the whotype matches
-		if ace.WhoType == whoType {
-			ace.setFlags(flags)
-		}
+	if err := checkWhoType(whoType); err != nil {
+		return err
 	}
-
-	return nil
+	return acl.unwrap().SetFlagsByWhoType(v2Flags(flags), whoType)
 }
 
 func (acl *NFS4ACL) SetFlagsByWho(flags uint32, who string) error {
-	//iterate our ace's
-	for _, ace := range acl.aceList {
-		//and only set if the who matches
-		if ace.Who == who {
-			ace.setFlags(flags)
-		}
-	}
-
-	return nil
+	return acl.unwrap().SetFlagsByWho(v2Flags(flags), who)
 }
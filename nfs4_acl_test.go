@@ -0,0 +1,151 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl_test
+
+import (
+	"bytes"
+	"github.com/cclose/libnfs4acl-go"
+	v2 "github.com/cclose/libnfs4acl-go/v2"
+	"testing"
+)
+
+func TestNFS4ACLRoundTrip(t *testing.T) {
+	var acl nfs4acl.NFS4ACL
+	acl.AddACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0,
+		nfs4acl.NFS4_ACE_READ_DATA|nfs4acl.NFS4_ACE_WRITE_DATA, nfs4acl.NFS4_ACL_WHO_OWNER_STRING)
+	acl.AddACE(nfs4acl.NFS4_ACE_ACCESS_DENIED_ACE_TYPE, nfs4acl.NFS4_ACE_IDENTIFIER_GROUP,
+		nfs4acl.NFS4_ACE_WRITE_DATA, "staff@example.com")
+
+	xattr, err := acl.PackXAttr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xattr) != acl.XAttrSize() {
+		t.Errorf("packed %d bytes, XAttrSize says %d", len(xattr), acl.XAttrSize())
+	}
+
+	loaded, err := nfs4acl.XAttrLoad(xattr, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := loaded.PackXAttr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, xattr) {
+		t.Errorf("repacked as %x, want %x", again, xattr)
+	}
+}
+
+func TestNFS4ACLEditing(t *testing.T) {
+	var acl nfs4acl.NFS4ACL
+	acl.AddACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, nfs4acl.NFS4_ACE_READ_DATA, nfs4acl.NFS4_ACL_WHO_OWNER_STRING)
+	acl.AddACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, nfs4acl.NFS4_ACE_READ_DATA, nfs4acl.NFS4_ACL_WHO_EVERYONE_STRING)
+
+	if err := acl.ApplyAccessMaskByWhoType(nfs4acl.NFS4_ACE_WRITE_DATA, nfs4acl.NFS4_ACL_WHO_OWNER); err != nil {
+		t.Fatal(err)
+	}
+	if err := acl.ApplyAccessMaskByWhoType(nfs4acl.NFS4_ACE_WRITE_DATA, nfs4acl.NFS4_ACL_WHO_NAMED); err == nil {
+		t.Error("named who type accepted")
+	}
+	//ANONYMOUS@ in v2, unknown here
+	if err := acl.ApplyAccessMaskByWhoType(nfs4acl.NFS4_ACE_WRITE_DATA, nfs4acl.NFS4_ACL_WHO_EVERYONE+1); err == nil {
+		t.Error("unknown who type accepted")
+	}
+
+	var want nfs4acl.NFS4ACL
+	want.AddACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0,
+		nfs4acl.NFS4_ACE_READ_DATA|nfs4acl.NFS4_ACE_WRITE_DATA, nfs4acl.NFS4_ACL_WHO_OWNER_STRING)
+	want.AddACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, nfs4acl.NFS4_ACE_READ_DATA, nfs4acl.NFS4_ACL_WHO_EVERYONE_STRING)
+	got, _ := acl.PackXAttr()
+	wantXattr, _ := want.PackXAttr()
+	if !bytes.Equal(got, wantXattr) {
+		t.Errorf("got %x, want %x", got, wantXattr)
+	}
+
+	acl.ClearACEs()
+	if acl.XAttrSize() != nfs4acl.ATOM_SIZE {
+		t.Errorf("cleared acl takes %d bytes", acl.XAttrSize())
+	}
+}
+
+func TestAceGetWhoType(t *testing.T) {
+	for who, want := range map[string]uint{
+		"OWNER@":          nfs4acl.NFS4_ACL_WHO_OWNER,
+		"EVERYONE@":       nfs4acl.NFS4_ACL_WHO_EVERYONE,
+		"ANONYMOUS@":      nfs4acl.NFS4_ACL_WHO_NAMED,
+		"1000":            nfs4acl.NFS4_ACL_WHO_NAMED,
+		"bob@example.com": nfs4acl.NFS4_ACL_WHO_NAMED,
+	} {
+		if got := nfs4acl.AceGetWhoType(who); got != want {
+			t.Errorf("%s: who type %d, want %d", who, got, want)
+		}
+	}
+}
+
+func TestPseudoFlags(t *testing.T) {
+	if nfs4acl.NFS4_ACE_OWNER != 0x80 || nfs4acl.NFS4_ACE_GROUP != 0x100 || nfs4acl.NFS4_ACE_EVERYONE != 0x200 {
+		t.Fatal("pseudo flags moved")
+	}
+
+	var acl nfs4acl.NFS4ACL
+	acl.AddACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, nfs4acl.NFS4_ACE_FILE_INHERIT_ACE|nfs4acl.NFS4_ACE_OWNER,
+		nfs4acl.NFS4_ACE_READ_DATA, nfs4acl.NFS4_ACL_WHO_OWNER_STRING)
+	acl.AddACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, nfs4acl.NFS4_ACE_READ_DATA, nfs4acl.NFS4_ACL_WHO_EVERYONE_STRING)
+	if err := acl.ApplyFlagsByWho(nfs4acl.NFS4_ACE_EVERYONE, nfs4acl.NFS4_ACL_WHO_EVERYONE_STRING); err != nil {
+		t.Fatal(err)
+	}
+
+	xattr, err := acl.PackXAttr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := v2.XAttrLoad(xattr, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the pseudo flags never go over the wire
+	aces := loaded.ACEs()
+	if want := v2.NFS4_ACE_FILE_INHERIT_ACE; aces[0].Flags != want {
+		t.Errorf("OWNER@ flags %#x, want %#x", uint32(aces[0].Flags), uint32(want))
+	}
+	if aces[1].Flags != 0 {
+		t.Errorf("EVERYONE@ flags %#x, want none", uint32(aces[1].Flags))
+	}
+}
+
+func TestInheritedSurvives(t *testing.T) {
+	inherited := v2.NewACL(true, v2.NewACE(v2.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, v2.NFS4_ACE_INHERITED_ACE,
+		v2.NFS4_ACE_READ_DATA, v2.NFS4_ACL_WHO_EVERYONE_STRING))
+	xattr, err := inherited.PackXAttr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acl, err := nfs4acl.XAttrLoad(xattr, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl.ApplyAccessMask(nfs4acl.NFS4_ACE_EXECUTE)
+	repacked, err := acl.PackXAttr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := v2.XAttrLoad(repacked, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ace := loaded.ACEs()[0]; ace.Flags != v2.NFS4_ACE_INHERITED_ACE {
+		t.Errorf("flags %#x after editing, want INHERITED_ACE", uint32(ace.Flags))
+	}
+}
+
+func TestPackXAttrValidates(t *testing.T) {
+	for _, who := range []string{"", "bad\x00who", "\xff"} {
+		var acl nfs4acl.NFS4ACL
+		acl.AddACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, nfs4acl.NFS4_ACE_READ_DATA, who)
+		if _, err := acl.PackXAttr(); err == nil {
+			t.Errorf("who %q packed", who)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-R] [--strip-inherit-only] source destination...\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	var recursive, strip bool
+	flag.BoolVar(&recursive, "R", false, "mirror the ACLs of the whole source tree onto each destination tree")
+	flag.BoolVar(&recursive, "recursive", false, "mirror the ACLs of the whole source tree onto each destination tree")
+	flag.BoolVar(&strip, "strip-inherit-only", false, "drop inherit-only ACEs and inheritance flags when the destination is a file")
+	flag.Usage = usage
+
+	flag.Parse()
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	source := flag.Arg(0)
+	for _, destination := range flag.Args()[1:] {
+		var err error
+		if recursive {
+			err = copyTree(source, destination, strip)
+		} else {
+			var acl *nfs4acl.ACL
+			if acl, err = nfs4acl.GetACL(source); err == nil {
+				err = copyACL(acl, destination, strip)
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+//Copies the ACL of every path under source onto the same relative path under
+//destination. Paths missing from the destination tree are reported and
+//skipped, as are symlinks there, along with everything below them
+func copyTree(source, destination string, strip bool) error {
+	return nfs4acl.WalkACLs(source, nfs4acl.WalkOptions{}, func(path string, info fs.FileInfo, acl *nfs4acl.ACL, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, rel)
+		if write, err := checkTarget(target, info.IsDir()); !write {
+			return err
+		}
+
+		return copyACL(acl, target, strip)
+	})
+}
+
+//Reports whether copyTree should write target, the counterpart of a source
+//path that is a directory if isDir. If not, err is what the walk returns:
+//SkipDir for a symlink standing in for a directory, so nothing is written
+//through it
+func checkTarget(target string, isDir bool) (write bool, err error) {
+	info, err := os.Lstat(target)
+	switch {
+	case os.IsNotExist(err):
+		log.Printf("skipping %s: not present in destination", target)
+		return false, nil
+	case err != nil:
+		return false, err
+	case info.Mode()&fs.ModeSymlink != 0:
+		log.Printf("skipping %s: symbolic link in destination", target)
+		if isDir {
+			return false, filepath.SkipDir
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+//Writes acl to target, adjusted to the target type if requested. Symlinks
+//are refused, the write would land on whatever they point to
+func copyACL(acl *nfs4acl.ACL, target string, strip bool) error {
+	info, err := os.Lstat(target)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("%s: refusing to write through a symbolic link", target)
+	}
+
+	acl = acl.Clone()
+	acl.SetIsDirectory(info.IsDir())
+	if strip && !info.IsDir() {
+		acl.StripInheritOnly()
+	}
+	return nfs4acl.SetACL(target, acl)
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"github.com/cclose/libnfs4acl-go/v2"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckTarget(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	dst := t.TempDir()
+	outside := t.TempDir()
+	file := filepath.Join(dst, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dst, "dirlink")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := os.Symlink(file, filepath.Join(dst, "filelink")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name  string
+		isDir bool
+		write bool
+		err   error
+	}{
+		{"file", false, true, nil},
+		{"missing", false, false, nil},
+		{"filelink", false, false, nil},
+		{"dirlink", true, false, filepath.SkipDir},
+	} {
+		write, err := checkTarget(filepath.Join(dst, test.name), test.isDir)
+		if write != test.write || err != test.err {
+			t.Errorf("%s: write %t, err %v, want %t and %v", test.name, write, err, test.write, test.err)
+		}
+	}
+}
+
+func TestCopyACLRefusesSymlink(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	//any other error would come from the write, which must not be attempted
+	err := copyACL(nfs4acl.NewACL(true), link, false)
+	if err == nil || !strings.Contains(err.Error(), "symbolic link") {
+		t.Errorf("write through a symlink: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+//Exit codes follow diff(1)
+const (
+	EXIT_SAME    = 0
+	EXIT_DIFFER  = 1
+	EXIT_TROUBLE = 2
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: %s path1 path2\n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "       %s -f saved_acl [-p saved_path] path\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	savedFile := flag.String("f", "", "compare path against the ACL saved in `file` (nfs4_getfacl output or a spec file)")
+	savedPath := flag.String("p", "", "compare against the \"# file:\" section of `path` in the saved file instead of the one of path")
+	flag.Usage = usage
+
+	flag.Parse()
+	if (*savedFile == "" && (flag.NArg() != 2 || *savedPath != "")) || (*savedFile != "" && flag.NArg() != 1) {
+		flag.Usage()
+		os.Exit(EXIT_TROUBLE)
+	}
+
+	var fromName, toName string
+	var from, to *nfs4acl.ACL
+	var err error
+	if *savedFile != "" {
+		fromName, toName = *savedFile, flag.Arg(0)
+		if to, err = nfs4acl.GetACL(toName); err == nil {
+			from, err = loadSaved(fromName, *savedPath, toName, to)
+		}
+	} else {
+		fromName, toName = flag.Arg(0), flag.Arg(1)
+		if from, err = nfs4acl.GetACL(fromName); err == nil {
+			to, err = nfs4acl.GetACL(toName)
+		}
+	}
+	if err != nil {
+		log.Print(err)
+		os.Exit(EXIT_TROUBLE)
+	}
+
+	diff := nfs4acl.DiffACLs(from, to)
+	if !diff.Changed() {
+		os.Exit(EXIT_SAME)
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", fromName, toName)
+	fmt.Print(diff)
+	os.Exit(EXIT_DIFFER)
+}
+
+//Reads a saved ACL from a dump or spec file. A recursive dump holds several
+//"# file:" sections: the one of savedPath is used or, without it, the one of
+//the live path. A file with a single section needs no path. The live ACL is
+//used as the template so both sides agree on the file type
+func loadSaved(savedFile, savedPath, livePath string, live *nfs4acl.ACL) (*nfs4acl.ACL, error) {
+	f, err := os.Open(savedFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	specs, err := nfs4acl.ParseSpecFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", savedFile, err)
+	}
+	section, err := selectSection(specs, savedPath, livePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", savedFile, err)
+	}
+
+	saved := live.Clone()
+	aces, err := saved.ParseACEs(section.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", savedFile, err)
+	}
+
+	saved.ClearACEs()
+	for _, ace := range aces {
+		saved.AppendACE(ace)
+	}
+	return saved, nil
+}
+
+//Picks the section of savedPath or, if empty, of livePath. A lone section is
+//taken as is unless savedPath asks for another
+func selectSection(specs []nfs4acl.PathSpec, savedPath, livePath string) (nfs4acl.PathSpec, error) {
+	if len(specs) == 1 && (savedPath == "" || samePath(specs[0].Path, savedPath)) {
+		return specs[0], nil
+	}
+
+	want := savedPath
+	if want == "" {
+		want = livePath
+	}
+	for _, spec := range specs {
+		if spec.Path != "" && samePath(spec.Path, want) {
+			return spec, nil
+		}
+	}
+
+	if len(specs) == 0 {
+		return nfs4acl.PathSpec{}, fmt.Errorf("no acl")
+	}
+	if savedPath == "" {
+		return nfs4acl.PathSpec{}, fmt.Errorf("%d sections and none for %s, choose one with -p", len(specs), want)
+	}
+	return nfs4acl.PathSpec{}, fmt.Errorf("no section for %s", want)
+}
+
+//Compares a path from a dump with one given on the command line, either of
+//them possibly relative to the working directory
+func samePath(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
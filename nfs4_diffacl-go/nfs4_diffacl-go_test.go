@@ -0,0 +1,70 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"github.com/cclose/libnfs4acl-go/v2"
+	"strings"
+	"testing"
+)
+
+const twoSectionDump = `# file: /srv/a
+A::OWNER@:rw
+A::EVERYONE@:r
+
+# file: /srv/b
+A::OWNER@:rwx
+`
+
+func parseDump(t *testing.T, dump string) []nfs4acl.PathSpec {
+	t.Helper()
+	specs, err := nfs4acl.ParseSpecFile(strings.NewReader(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return specs
+}
+
+func TestSelectSection(t *testing.T) {
+	specs := parseDump(t, twoSectionDump)
+
+	for _, test := range []struct {
+		savedPath, livePath string
+		want                string
+	}{
+		{"", "/srv/b", "A::OWNER@:rwx"},
+		{"", "/srv/./a", "A::OWNER@:rw\nA::EVERYONE@:r"},
+		{"/srv/b", "/mnt/migrated/b", "A::OWNER@:rwx"},
+	} {
+		section, err := selectSection(specs, test.savedPath, test.livePath)
+		if err != nil {
+			t.Errorf("%q, %q: %v", test.savedPath, test.livePath, err)
+			continue
+		}
+		if section.Spec != test.want {
+			t.Errorf("%q, %q: got %q, want %q", test.savedPath, test.livePath, section.Spec, test.want)
+		}
+	}
+
+	//the sections must not be merged into one acl
+	if _, err := selectSection(specs, "", "/srv/c"); err == nil {
+		t.Error("several sections and no match accepted")
+	}
+	if _, err := selectSection(specs, "/srv/c", "/srv/a"); err == nil {
+		t.Error("missing -p section accepted")
+	}
+}
+
+func TestSelectSectionSingle(t *testing.T) {
+	for _, dump := range []string{"# file: /srv/a\nA::OWNER@:rw\n", "A::OWNER@:rw\n"} {
+		section, err := selectSection(parseDump(t, dump), "", "/elsewhere")
+		if err != nil {
+			t.Errorf("%q: %v", dump, err)
+		} else if section.Spec != "A::OWNER@:rw" {
+			t.Errorf("%q: got %q", dump, section.Spec)
+		}
+	}
+	if _, err := selectSection(parseDump(t, "# file: /srv/a\nA::OWNER@:rw\n"), "/srv/b", "/srv/a"); err == nil {
+		t.Error("lone section of another path accepted with -p")
+	}
+}
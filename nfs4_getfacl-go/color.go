@@ -0,0 +1,39 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"golang.org/x/term"
+	"os"
+)
+
+//Value of --color, one of auto, always or never
+type colorMode string
+
+func (c *colorMode) String() string {
+	return string(*c)
+}
+
+func (c *colorMode) Set(value string) error {
+	switch value {
+	case "true":
+		*c = "auto"
+	case "false":
+		*c = "never"
+	case "auto", "always", "never":
+		*c = colorMode(value)
+	default:
+		return fmt.Errorf("invalid color mode %q, expected auto, always or never", value)
+	}
+	return nil
+}
+
+func (c *colorMode) Type() string {
+	return "when"
+}
+
+//Reports whether f is a terminal, so colors are only sent where they render
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
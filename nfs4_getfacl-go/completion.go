@@ -0,0 +1,25 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+//Prints the completion script for the shell named by its argument
+var completionCmd = &cobra.Command{
+	Use:       "completion bash|zsh|fish",
+	Short:     "Print a shell completion script",
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Args:      cobra.ExactValidArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		switch args[0] {
+		case "zsh":
+			return cmd.Root().GenZshCompletion(out)
+		case "fish":
+			return cmd.Root().GenFishCompletion(out, true)
+		}
+		return cmd.Root().GenBashCompletionV2(out, true)
+	},
+}
@@ -5,29 +5,270 @@
 package main
 
 import (
-	"flag"
-	//"fmt"
-	"github.com/cclose/libnfs4acl-go"
+	"encoding/json"
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/internal/clireport"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/spf13/cobra"
+	"io/fs"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 )
 
+//The command line, kept so usage errors can print its usage
+var rootCmd = &cobra.Command{
+	Use:   "nfs4_getfacl-go path...",
+	Short: "Print the NFSv4 ACLs of files and directories",
+	Args:  cobra.ArbitraryArgs,
+}
+
+type printOptions struct {
+	verbose      bool
+	longPerms    bool
+	resolveOwner bool
+	align        bool
+	grouped      bool
+	color        bool
+	numeric      bool
+	resolve      bool
+	omitHeader   bool
+	compat       bool
+	format       string
+	view         aceView
+}
+
 func main() {
-	//recursive := flag.Bool("recursive", false, "recurse into directories")
-	//omitheader := flag.Bool("omit-header", false, "omit header for each path")
-	verbose := flag.Bool("verbose", false, "verbosity of output")
+	var recursive, jsonl, rightsMatrix, csvRows bool
+	var summaryJSON string
+	var opts printOptions
+	var report runReport
+	color := colorMode("never")
+	flags := rootCmd.Flags()
+	flags.BoolVarP(&recursive, "recursive", "R", false, "recurse into directories")
+	flags.BoolVarP(&opts.numeric, "numeric", "n", false, "display user and group IDs numerically where possible")
+	flags.BoolVar(&opts.resolve, "resolve-ids", false, "display numeric user and group IDs in the ACL as names")
+	flags.BoolVarP(&opts.omitHeader, "omit-header", "H", false, "omit header for each path")
+	flags.BoolVar(&opts.verbose, "verbose", false, "verbosity of output")
+	flags.BoolVarP(&opts.longPerms, "long", "l", false, "list permissions by name, one per line")
+	flags.BoolVar(&opts.align, "align", false, "pad the fields of each ACL into columns")
+	flags.BoolVar(&opts.grouped, "grouped", false, "list the ACEs under the user or group they apply to")
+	flags.Var(&color, "color", "color the output: auto, always or never; plain --color means auto")
+	flags.Lookup("color").NoOptDefVal = "auto"
+	rootCmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions([]string{"auto", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("sort", cobra.FixedCompletions([]string{SORT_WHO, SORT_TYPE}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("filter-type", cobra.FixedCompletions([]string{"allow", "deny", "audit", "alarm"}, cobra.ShellCompDirectiveNoFileComp))
+	flags.BoolVar(&opts.resolveOwner, "resolve-owner", false, "show the owning user and group next to OWNER@ and GROUP@")
+	flags.BoolVar(&report.skipErrors, "skip-errors", false, "report paths that fail and carry on, exiting with 1 at the end")
+	flags.BoolVar(&jsonl, "jsonl", false, "print one JSON object per path instead of the usual output")
+	flags.BoolVar(&csvRows, "csv", false, "print one CSV row per ACE, under a path,index,type,flags,who,perms,perm_names header, instead of the usual output")
+	flags.BoolVar(&rightsMatrix, "rights-matrix", false, "instead of the ACLs, print how many paths each user or group may read, write or administer; with --filter-who the paths are listed too")
+	flags.StringVar(&summaryJSON, "summary-json", "", "write a summary of the run as JSON to `file`, or stdout if -")
+	flags.BoolVar(&opts.compat, "compat", false, "output exactly what the C nfs4_getfacl prints, for scripts parsing it")
+	flags.StringVar(&opts.format, "format", "", "print each ACE through the Go `template`, e.g. '{{.Who}}\\t{{.PermsVerbose}}', where \\t and \\n are a tab and a newline; replaces --verbose, --long, --align and --grouped")
+	flags.StringVar(&opts.view.sortBy, "sort", "", "display the ACEs sorted by `key`, who or type; their order in the ACL is unchanged")
+	flags.StringArrayVar(&opts.view.whos, "filter-who", nil, "display only the ACEs of `who`, as stored in the ACL; repeat for several")
+	flags.StringSliceVar(&opts.view.typeNames, "filter-type", nil, "display only the ACEs of the given `types`, e.g. D or allow,deny")
+	flags.Bool("version", false, "print version information and exit")
+	rootCmd.MarkFlagFilename("summary-json")
+	rootCmd.AddCommand(completionCmd, versionCmd)
+	rootCmd.Version = buildVersion()
+	rootCmd.SetVersionTemplate("{{.Name}} {{.Version}}\n")
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		clireport.UsageError(rootCmd, err.Error())
+		return err
+	})
+
+	rootCmd.Run = func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			clireport.UsageError(rootCmd, "no paths given")
+		}
+		opts.color = color == "always" || (color == "auto" && isTerminal(os.Stdout))
+		if opts.compat && (opts.verbose || opts.longPerms || opts.align || opts.grouped || color == "always" ||
+			opts.resolveOwner || opts.numeric || opts.resolve || opts.view.set()) {
+			clireport.UsageError(rootCmd, "--compat can't be combined with options the C nfs4_getfacl lacks")
+		}
+		//auto never colors compatible output
+		opts.color = opts.color && !opts.compat
+		if err := opts.view.parse(); err != nil {
+			clireport.UsageError(rootCmd, err.Error())
+		}
+		if opts.format != "" {
+			opts.format = formatEscapes.Replace(opts.format)
+			if opts.compat || jsonl || opts.grouped {
+				clireport.UsageError(rootCmd, "--format can't be combined with --compat, --grouped or --jsonl")
+			}
+			//an ACE to execute on catches unknown fields as well as syntax errors
+			probe := nfs4acl.NewACL(false, nfs4acl.NewACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, 0, nfs4acl.NFS4_ACL_WHO_OWNER_STRING))
+			if _, err := probe.FormatTemplate(opts.format); err != nil {
+				clireport.UsageError(rootCmd, fmt.Sprintf("invalid --format: %v", err))
+			}
+		}
+		var rights *nfs4acl.RightsMatrix
+		if rightsMatrix {
+			if opts.compat || jsonl || opts.format != "" {
+				clireport.UsageError(rootCmd, "--rights-matrix can't be combined with --compat, --format or --jsonl")
+			}
+			//a narrowed down matrix is short enough to list its paths
+			rights = nfs4acl.NewRightsMatrix(len(opts.view.whos) > 0)
+		}
+		var csvOut *nfs4acl.CSVWriter
+		if csvRows {
+			if opts.compat || jsonl || opts.format != "" || rightsMatrix {
+				clireport.UsageError(rootCmd, "--csv can't be combined with --compat, --format, --jsonl or --rights-matrix")
+			}
+			csvOut = nfs4acl.NewCSVWriter(os.Stdout)
+		}
+		if jsonl {
+			if opts.compat {
+				clireport.UsageError(rootCmd, "--jsonl and --compat are mutually exclusive")
+			}
+			report.jsonl = json.NewEncoder(os.Stdout)
+		}
+
+		//hands a fetched ACL to whichever output was asked for
+		output := func(path string, info fs.FileInfo, acl *nfs4acl.ACL) {
+			acl = opts.view.apply(acl)
+			report.result(path, acl)
+			switch {
+			case rights != nil:
+				rights.Add(path, acl)
+			case csvOut != nil:
+				if err := csvOut.Write(path, displayWhos(acl, opts)); err != nil {
+					log.Fatal(err)
+				}
+			case !jsonl:
+				printEntry(path, info, acl, opts)
+			}
+		}
+
+		aborted := false
+		for _, filePath := range args {
+			if recursive {
+				err := nfs4acl.WalkACLs(filePath, nfs4acl.WalkOptions{Retry: nfs4acl.DefaultRetryPolicy()}, func(path string, info fs.FileInfo, acl *nfs4acl.ACL, err error) error {
+					if err != nil {
+						return report.record(path, err)
+					}
+					output(path, info, acl)
+					return nil
+				})
+				if err != nil {
+					report.abort(filePath, err, clireport.EXIT_FAILED)
+					aborted = true
+					break
+				}
+				continue
+			}
+
+			info, err := os.Stat(filePath)
+			if err == nil {
+				var acl *nfs4acl.ACL
+				if acl, err = nfs4acl.LoadACL(filePath, info.IsDir()); err == nil {
+					output(filePath, info, acl)
+				}
+			}
+			if err = report.record(filePath, err); err != nil {
+				report.abort(filePath, err, clireport.EXIT_FAILED)
+				aborted = true
+				break
+			}
+		}
+
+		if rights != nil && !aborted {
+			printRights(rights, len(opts.view.whos) > 0)
+		}
+		if csvOut != nil {
+			if err := csvOut.Flush(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if recursive {
+			report.print()
+		}
+		if summaryJSON != "" {
+			if err := report.writeJSON(summaryJSON); err != nil {
+				report.abort(summaryJSON, err, clireport.EXIT_FAILED)
+			}
+		}
+		os.Exit(report.exitCode())
+	}
 
-	flag.Parse()
-	if flag.NArg() < 1 {
-		flag.Usage()
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(clireport.EXIT_USAGE)
 	}
+}
 
-	for i := 0; i < flag.NArg(); i++ {
-		filePath := flag.Arg(i)
-		acls, err := nfs4acl.Nfs4_getacl_for_path(filePath)
+//Backslash escapes understood in --format, as find -printf does, since
+//shells don't expand them inside quotes
+var formatEscapes = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n")
+
+//Prints one path's ACL, preceded by the getfacl style header block. Paths
+//with no ACE passing the filters are left out
+func printEntry(path string, info fs.FileInfo, acl *nfs4acl.ACL, opts printOptions) {
+	if opts.view.filtering() && acl.Len() == 0 {
+		return
+	}
+	if !opts.omitHeader {
+		//same layout as the C nfs4_getfacl so existing parsers keep working,
+		//apart from the owner and group lines it lacks
+		fmt.Printf("# file: %s\n", path)
+		if uid, gid, ok := nfs4acl.OwnerIDs(info); ok && !opts.compat {
+			fmt.Printf("# owner: %s\n", ownerName(uid, opts.numeric))
+			fmt.Printf("# group: %s\n", groupName(gid, opts.numeric))
+		}
+	}
+
+	acl = displayWhos(acl, opts)
+	if opts.format != "" {
+		out, err := acl.FormatTemplate(opts.format)
 		if err != nil {
 			log.Fatal(err)
-		} else {
-			acls.PrintACL(*verbose)
+		}
+		fmt.Print(out)
+	} else {
+		acl.PrintACLWith(nfs4acl.PrintOptions{
+			Verbose:      opts.verbose,
+			LongPerms:    opts.longPerms,
+			ResolveOwner: opts.resolveOwner,
+			FileInfo:     info,
+			Align:        opts.align,
+			Grouped:      opts.grouped,
+			Color:        opts.color,
+		})
+	}
+
+	if !opts.omitHeader {
+		fmt.Println()
+	}
+}
+
+//Returns acl with its whos as --numeric or --resolve-ids want them
+func displayWhos(acl *nfs4acl.ACL, opts printOptions) *nfs4acl.ACL {
+	if opts.numeric {
+		return acl.WithNumericWhos()
+	} else if opts.resolve {
+		return acl.WithResolvedWhos(nil)
+	}
+	return acl
+}
+
+//Resolves a uid to a user name, falling back to the number
+func ownerName(uid uint32, numeric bool) string {
+	if !numeric {
+		if name, err := nfs4acl.GetPrincipalResolver().UserName(uid); err == nil {
+			return name
+		}
+	}
+	return strconv.FormatUint(uint64(uid), 10)
+}
+
+//Resolves a gid to a group name, falling back to the number
+func groupName(gid uint32, numeric bool) string {
+	if !numeric {
+		if name, err := nfs4acl.GetPrincipalResolver().GroupName(gid); err == nil {
+			return name
 		}
 	}
+	return strconv.FormatUint(uint64(gid), 10)
 }
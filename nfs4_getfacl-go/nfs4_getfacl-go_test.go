@@ -0,0 +1,57 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"github.com/cclose/libnfs4acl-go/internal/clireport"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+//Set to the arguments, separated by newlines, when the test binary runs main
+const MAIN_ARGS_ENV = "NFS4_GETFACL_TEST_ARGS"
+
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(MAIN_ARGS_ENV); ok {
+		os.Args = append([]string{"nfs4_getfacl-go"}, strings.Split(args, "\n")...)
+		main()
+		os.Exit(clireport.EXIT_OK)
+	}
+	os.Exit(m.Run())
+}
+
+//Runs main with args in a child process and returns its exit code
+func runMain(t *testing.T, args ...string) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), MAIN_ARGS_ENV+"="+strings.Join(args, "\n"))
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return clireport.EXIT_OK
+}
+
+func TestCompatRejectsViewFlags(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"--compat", "--sort", "who"},
+		{"--compat", "--filter-who", "OWNER@"},
+		{"--compat", "--filter-type", "allow"},
+	} {
+		if code := runMain(t, append(args, dir)...); code != clireport.EXIT_USAGE {
+			t.Errorf("%q: exit code %d, want %d", args, code, clireport.EXIT_USAGE)
+		}
+	}
+
+	//the path has no ACL here, so only the exit code tells the cases apart
+	if code := runMain(t, "--compat", dir); code == clireport.EXIT_USAGE {
+		t.Error("--compat alone is a usage error")
+	}
+}
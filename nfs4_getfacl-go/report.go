@@ -0,0 +1,110 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/internal/clireport"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"log"
+	"sync"
+)
+
+//Outcomes of a path as streamed by --jsonl
+const (
+	RESULT_OK      = "ok"
+	RESULT_SKIPPED = "skipped" //no NFSv4 ACL support, with --skip-errors
+	RESULT_ERROR   = "error"
+)
+
+//One line of --jsonl output
+type pathResult struct {
+	Path   string       `json:"path"`
+	Result string       `json:"result"`
+	ACL    *nfs4acl.ACL `json:"acl,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+//Collects per-path results. With skipErrors failures are logged and the run
+//goes on, and paths on filesystems without NFSv4 ACLs are skipped quietly;
+//otherwise the first failure is handed back to abort the run
+type runReport struct {
+	skipErrors bool
+	jsonl      *json.Encoder //set to stream every result
+
+	mu      sync.Mutex
+	summary clireport.Summary //also written by --summary-json
+}
+
+//Records the ACL read from path
+func (r *runReport) result(path string, acl *nfs4acl.ACL) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Scanned++
+	r.emit(pathResult{Path: path, Result: RESULT_OK, ACL: acl})
+}
+
+//Writes a line of --jsonl output. Called with mu held, so lines never
+//interleave
+func (r *runReport) emit(line pathResult) {
+	if r.jsonl == nil {
+		return
+	}
+	if err := r.jsonl.Encode(line); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//Records the failure of path, if any. Returns nil when the run should
+//continue, the error to end it with otherwise
+func (r *runReport) record(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.skipErrors && nfs4acl.IsNotSupported(err) {
+		r.summary.SkippedUnsupported++
+		r.emit(pathResult{Path: path, Result: RESULT_SKIPPED, Error: err.Error()})
+		return nil
+	}
+	r.emit(pathResult{Path: path, Result: RESULT_ERROR, Error: err.Error()})
+	err = r.summary.Fail(path, err)
+	if !r.skipErrors {
+		return err
+	}
+	log.Print(err)
+	return nil
+}
+
+//Ends the run early with err, exiting with code once the summary is out.
+//path is what err is about, if anything
+func (r *runReport) abort(path string, err error, code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Abort(path, err, code)
+}
+
+//Prints the summary to stderr
+func (r *runReport) print() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Print(fmt.Sprintf("scanned: %d, skipped (unsupported): %d, errors: %d",
+		r.summary.Scanned, r.summary.SkippedUnsupported, len(r.summary.Errors)))
+}
+
+//Writes the summary as JSON to file, "-" meaning stdout
+func (r *runReport) writeJSON(file string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return clireport.WriteJSON(file, r.summary)
+}
+
+//Exit code for the run so far
+func (r *runReport) exitCode() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.summary.ExitCode()
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"log"
+	"os"
+)
+
+//Prints the matrix built by --rights-matrix and, when it kept them, the
+//paths behind every count, grouped by principal and class
+func printRights(rights *nfs4acl.RightsMatrix, listPaths bool) {
+	if err := rights.WriteTable(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	if !listPaths {
+		return
+	}
+
+	for _, principal := range rights.Principals() {
+		for _, class := range nfs4acl.RightsClasses {
+			paths := rights.Paths(principal, class)
+			if len(paths) == 0 {
+				continue
+			}
+			fmt.Printf("\n# %s: %s\n", principal, class)
+			for _, path := range paths {
+				fmt.Println(path)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"runtime/debug"
+)
+
+//Prints the version of the running binary
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version information",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", cmd.Root().Name(), buildVersion())
+	},
+}
+
+//Describes the running binary from the build info the go tool embeds: the
+//module version, the commit it was built from and the Go version, so bug
+//reports say exactly what was running
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(unknown)"
+	}
+
+	version := info.Main.Version
+	if version == "" {
+		version = "(devel)"
+	}
+	var commit, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			commit = setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" {
+				modified = ", modified"
+			}
+		}
+	}
+	if commit != "" {
+		version += fmt.Sprintf(" (commit %s%s)", commit, modified)
+	}
+
+	return version + " " + info.GoVersion
+}
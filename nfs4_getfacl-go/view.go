@@ -0,0 +1,87 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"sort"
+)
+
+//Values of --sort
+const (
+	SORT_WHO  = "who"
+	SORT_TYPE = "type"
+)
+
+//Which ACEs are displayed and in what order, set by --filter-who,
+//--filter-type and --sort. Only the output changes, never the ACL itself
+type aceView struct {
+	whos      []string
+	typeNames []string
+	types     []nfs4acl.AceType
+	sortBy    string
+}
+
+//Checks the flag values, resolving the type names
+func (v *aceView) parse() error {
+	switch v.sortBy {
+	case "", SORT_WHO, SORT_TYPE:
+	default:
+		return fmt.Errorf("invalid --sort %q, expected %s or %s", v.sortBy, SORT_WHO, SORT_TYPE)
+	}
+
+	for _, name := range v.typeNames {
+		aceType, err := nfs4acl.ParseAceType(name)
+		if err != nil {
+			return fmt.Errorf("invalid --filter-type: %w", err)
+		}
+		v.types = append(v.types, aceType)
+	}
+	return nil
+}
+
+//Reports whether any of the view flags was given, which --compat rejects
+func (v *aceView) set() bool {
+	return len(v.whos) > 0 || len(v.typeNames) > 0 || v.sortBy != ""
+}
+
+//Reports whether only some ACEs are displayed
+func (v *aceView) filtering() bool {
+	return len(v.whos) > 0 || len(v.types) > 0
+}
+
+//Returns acl as displayed: the ACEs matching any given who and any given
+//type, stably sorted when asked. acl itself is returned when nothing is set
+func (v *aceView) apply(acl *nfs4acl.ACL) *nfs4acl.ACL {
+	if !v.filtering() && v.sortBy == "" {
+		return acl
+	}
+
+	var aces []*nfs4acl.ACE
+	for _, ace := range acl.ACEs() {
+		if v.matches(ace) {
+			aces = append(aces, ace)
+		}
+	}
+
+	switch v.sortBy {
+	case SORT_WHO:
+		sort.SliceStable(aces, func(i, j int) bool { return aces[i].Who < aces[j].Who })
+	case SORT_TYPE:
+		sort.SliceStable(aces, func(i, j int) bool { return aces[i].AceType < aces[j].AceType })
+	}
+	return nfs4acl.NewACL(acl.IsDirectory(), aces...)
+}
+
+func (v *aceView) matches(ace *nfs4acl.ACE) bool {
+	whoOK := len(v.whos) == 0
+	for _, who := range v.whos {
+		whoOK = whoOK || ace.Who == who
+	}
+	typeOK := len(v.types) == 0
+	for _, aceType := range v.types {
+		typeOK = typeOK || ace.AceType == aceType
+	}
+	return whoOK && typeOK
+}
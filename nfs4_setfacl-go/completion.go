@@ -0,0 +1,86 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+//Prints the completion script for the shell named by its argument
+var completionCmd = &cobra.Command{
+	Use:       "completion bash|zsh|fish",
+	Short:     "Print a shell completion script",
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Args:      cobra.ExactValidArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		switch args[0] {
+		case "zsh":
+			return cmd.Root().GenZshCompletion(out)
+		case "fish":
+			return cmd.Root().GenFishCompletion(out, true)
+		}
+		return cmd.Root().GenBashCompletionV2(out, true)
+	},
+}
+
+//Characters offered for each field of an ACE. O, G and E are left out of the
+//flags as they can't be set directly
+var (
+	aceTypeChars = string([]rune{nfs4acl.TYPE_ALLOW, nfs4acl.TYPE_DENY, nfs4acl.TYPE_AUDIT, nfs4acl.TYPE_ALARM})
+	aceFlagChars = string([]rune{nfs4acl.FLAG_FILE_INHERIT, nfs4acl.FLAG_DIR_INHERIT,
+		nfs4acl.FLAG_NO_PROPAGATE_INHERIT, nfs4acl.FLAG_INHERIT_ONLY, nfs4acl.FLAG_SUCCESSFUL_ACCESS,
+		nfs4acl.FLAG_FAILED_ACCESS, nfs4acl.FLAG_GROUP, nfs4acl.FLAG_INHERITED})
+	acePermChars = string([]rune{nfs4acl.PERM_READ_DATA, nfs4acl.PERM_WRITE_DATA, nfs4acl.PERM_APPEND_DATA,
+		nfs4acl.PERM_EXECUTE, nfs4acl.PERM_DELETE, nfs4acl.PERM_DELETE_CHILD, nfs4acl.PERM_READ_ATTR,
+		nfs4acl.PERM_WRITE_ATTR, nfs4acl.PERM_READ_NAMED_ATTR, nfs4acl.PERM_WRITE_NAMED_ATTR,
+		nfs4acl.PERM_READ_ACL, nfs4acl.PERM_WRITE_ACL, nfs4acl.PERM_WRITE_OWNER, nfs4acl.PERM_SYNCHRONIZE,
+		nfs4acl.PERM_GENERIC_READ, nfs4acl.PERM_GENERIC_WRITE, nfs4acl.PERM_GENERIC_EXECUTE})
+	aceSpecialWhos = []string{nfs4acl.NFS4_ACL_WHO_OWNER_STRING, nfs4acl.NFS4_ACL_WHO_GROUP_STRING,
+		nfs4acl.NFS4_ACL_WHO_EVERYONE_STRING}
+)
+
+//Completes the ACE being typed in an acl_spec one field at a time: the type,
+//then the flag characters, the special whos and the permission characters.
+//Other whos are left to the user
+func completeACE(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	//only the last ACE of a list is being typed
+	start := strings.LastIndexAny(toComplete, ", ") + 1
+	fields := strings.Split(toComplete[start:], ":")
+	field := fields[len(fields)-1]
+	done := toComplete[:len(toComplete)-len(field)]
+
+	var chars string
+	var completions []string
+	switch len(fields) {
+	case 1:
+		for _, c := range aceTypeChars {
+			completions = append(completions, done+string(c)+":")
+		}
+		return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	case 2:
+		chars = aceFlagChars
+	case 3:
+		for _, who := range aceSpecialWhos {
+			completions = append(completions, done+who+":")
+		}
+		return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	case 4:
+		chars = acePermChars
+	default:
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	//each character may be given once, in any order
+	if len(fields) == 2 {
+		completions = append(completions, toComplete+":")
+	}
+	for _, c := range chars {
+		if !strings.ContainsRune(field, c) {
+			completions = append(completions, toComplete+string(c))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
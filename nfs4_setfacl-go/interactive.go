@@ -0,0 +1,73 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"io"
+	"os"
+	"strings"
+)
+
+//Asks before every change when -i is given, nil otherwise
+var prompt *confirmer
+
+//Shows each change and asks for confirmation, on stderr so the prompts don't
+//mix with other output
+type confirmer struct {
+	in   *bufio.Reader
+	out  io.Writer
+	all  bool //answered "all", apply the rest without asking
+	quit bool //answered "quit", apply nothing more
+}
+
+//Reads answers from in
+func newConfirmer(in io.Reader) *confirmer {
+	return &confirmer{
+		in:  bufio.NewReader(in),
+		out: os.Stderr,
+	}
+}
+
+//Prints how from would change into to for path and asks whether to go ahead.
+//End of input counts as quit
+func (c *confirmer) confirm(path string, from, to *nfs4acl.ACL) bool {
+	if c.quit {
+		return false
+	}
+	if c.all {
+		return true
+	}
+
+	fmt.Fprintf(c.out, "# file: %s\n", path)
+	fmt.Fprint(c.out, nfs4acl.DiffACLs(from, to))
+	for {
+		fmt.Fprint(c.out, "apply? [y]es, [N]o, [a]ll, [q]uit: ")
+		answer, err := c.in.ReadString('\n')
+		if err != nil && answer == "" {
+			fmt.Fprintln(c.out)
+			c.quit = true
+			return false
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		case "n", "no", "":
+			return false
+		case "a", "all":
+			c.all = true
+			return true
+		case "q", "quit":
+			c.quit = true
+			return false
+		}
+	}
+}
+
+//Reports whether the user asked to stop
+func (c *confirmer) stopped() bool {
+	return c != nil && c.quit
+}
@@ -5,29 +5,429 @@
 package main
 
 import (
-	"flag"
-	//"fmt"
-	"github.com/cclose/libnfs4acl-go"
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/internal/clireport"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/spf13/cobra"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 )
 
+//The command line, kept so usage errors can print its usage
+var rootCmd = &cobra.Command{
+	Use:   "nfs4_setfacl-go",
+	Short: "Manipulate the NFSv4 ACLs of files and directories",
+	Args:  cobra.ArbitraryArgs,
+}
+
+func usage(cmd *cobra.Command) error {
+	out := cmd.OutOrStderr()
+	if cmd.HasParent() {
+		fmt.Fprintf(out, "usage: %s\n", cmd.UseLine())
+		fmt.Fprint(out, cmd.Flags().FlagUsages())
+		return nil
+	}
+	fmt.Fprintf(out, "usage: %s [-R] [-L|-P] [-i] -a|-x|-s acl_spec path...\n", os.Args[0])
+	fmt.Fprintf(out, "       %s [-R] [-L|-P] [-i] -S spec_file|- [path...]\n", os.Args[0])
+	fmt.Fprintf(out, "       %s [-R] [-L|-P] [-i] -m from_ace to_ace path...\n", os.Args[0])
+	fmt.Fprintf(out, "       %s [-R|-i] --reset-inherited path...\n", os.Args[0])
+	fmt.Fprintf(out, "       %s [-i] --restore=file\n", os.Args[0])
+	fmt.Fprintf(out, "       %s completion bash|zsh|fish\n", os.Args[0])
+	fmt.Fprintf(out, "       %s version\n", os.Args[0])
+	fmt.Fprint(out, cmd.Flags().FlagUsages())
+	return nil
+}
+
 func main() {
-	//recursive := flag.Bool("recursive", false, "recurse into directories")
-	//omitheader := flag.Bool("omit-header", false, "omit header for each path")
-	//verbose := flag.Bool("verbose", false, "verbosity of output")
+	var recursive, logical, physical, dryRun, resetInherited, jsonl, interactive bool
+	var addSpec, deleteSpec, modifySpec, setSpec, specFile, restoreFile, summaryJSON string
+	var report runReport
+	flags := rootCmd.Flags()
+	flags.StringVarP(&addSpec, "add-spec", "a", "", "add the ACEs in `acl_spec`, optionally followed by a 1-based :index (default 1)")
+	flags.StringVarP(&deleteSpec, "remove-spec", "x", "", "delete the ACEs in `acl_spec`, or the ACE at a 1-based index")
+	flags.StringVarP(&modifySpec, "modify", "m", "", "replace `from_ace` with the ACE given as the first argument")
+	flags.StringVarP(&setSpec, "set-spec", "s", "", "replace the whole ACL with `acl_spec`")
+	flags.StringVarP(&specFile, "set-file", "S", "", "replace the whole ACL with the spec read from `file`, or stdin if -. \"# file:\" sections set the ACLs of their own paths")
+	flags.StringVar(&restoreFile, "restore", "", "restore the ACLs recorded in an nfs4_getfacl dump or spec `file` with \"# file:\" sections, or stdin if -")
+	flags.BoolVar(&resetInherited, "reset-inherited", false, "replace the ACL with the entries inherited from the parent directory")
+	flags.BoolVarP(&recursive, "recursive", "R", false, "recursively apply to all files and directories")
+	flags.BoolVarP(&logical, "logical", "L", false, "logical walk, follow symbolic links")
+	flags.BoolVarP(&physical, "physical", "P", false, "physical walk, do not follow symbolic links (default)")
+	flags.BoolVarP(&dryRun, "test", "t", false, "print the resulting ACLs and changes without applying them")
+	flags.BoolVar(&report.skipErrors, "skip-errors", false, "report paths that fail and carry on, exiting with 1 at the end")
+	flags.BoolVarP(&interactive, "interactive", "i", false, "show each change and ask before applying it")
+	flags.BoolVar(&report.zfs, "zfs", false, "follow the ZFS aclinherit property with --reset-inherited, and warn of what aclmode and aclinherit will do to the ACLs set")
+	flags.BoolVar(&jsonl, "jsonl", false, "print one JSON object per path as it completes, instead of the --test output")
+	flags.StringVar(&summaryJSON, "summary-json", "", "write a summary of the run as JSON to `file`, or stdout if -")
+	flags.Bool("version", false, "print version information and exit")
+	//verbose := flags.Bool("verbose", false, "verbosity of output")
+	flags.SortFlags = false
+	for _, name := range []string{"add-spec", "remove-spec", "modify", "set-spec"} {
+		rootCmd.RegisterFlagCompletionFunc(name, completeACE)
+	}
+	for _, name := range []string{"set-file", "restore", "summary-json"} {
+		rootCmd.MarkFlagFilename(name)
+	}
+	rootCmd.AddCommand(completionCmd, versionCmd)
+	rootCmd.Version = buildVersion()
+	rootCmd.SetVersionTemplate("{{.Name}} {{.Version}}\n")
+	rootCmd.SetUsageFunc(usage)
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		clireport.UsageError(rootCmd, err.Error())
+		return err
+	})
+
+	rootCmd.Run = func(cmd *cobra.Command, args []string) {
+		if logical && physical {
+			clireport.UsageError(rootCmd, "-L and -P are mutually exclusive")
+		}
+
+		operations := 0
+		for _, spec := range []string{addSpec, deleteSpec, modifySpec, setSpec, specFile, restoreFile} {
+			if spec != "" {
+				operations++
+			}
+		}
+		if resetInherited {
+			operations++
+		}
+		if operations != 1 {
+			clireport.UsageError(rootCmd, "exactly one of -a, -x, -m, -s, -S, --reset-inherited or --restore is required")
+		}
+		if summaryJSON == "-" && (dryRun || jsonl) {
+			clireport.UsageError(rootCmd, "--summary-json - can't be combined with --test or --jsonl, which write to stdout too")
+		}
+		report.dryRun = dryRun
+		if jsonl {
+			report.jsonl = json.NewEncoder(os.Stdout)
+		}
+		//every way out of the run below goes through the report
+		defer func() {
+			if recursive {
+				report.print()
+			}
+			if summaryJSON != "" {
+				if err := report.writeJSON(summaryJSON); err != nil {
+					report.abort(summaryJSON, err, clireport.EXIT_FAILED)
+				}
+			}
+			os.Exit(report.exitCode())
+		}()
+
+		if interactive {
+			if dryRun || jsonl {
+				clireport.UsageError(rootCmd, "-i can't be combined with --test or --jsonl")
+			}
+			if resetInherited && recursive {
+				clireport.UsageError(rootCmd, "-i can't be combined with -R --reset-inherited")
+			}
+			//answers can't come from stdin when the spec does
+			in := os.Stdin
+			if specFile == "-" || restoreFile == "-" {
+				tty, err := os.Open("/dev/tty")
+				if err != nil {
+					report.abort("/dev/tty", err, clireport.EXIT_FAILED)
+					return
+				}
+				defer tty.Close()
+				in = tty
+			}
+			prompt = newConfirmer(in)
+		}
+
+		if restoreFile != "" {
+			if err := restore(restoreFile, dryRun, &report); err != nil {
+				report.abort(restoreFile, err, clireport.EXIT_FAILED)
+			}
+			return
+		}
+
+		if resetInherited {
+			if dryRun && recursive {
+				clireport.UsageError(rootCmd, "--test can't be combined with -R --reset-inherited")
+			}
+			for _, filePath := range args {
+				if prompt.stopped() {
+					break
+				}
+				if err := report.record(filePath, resetPath(filePath, recursive, dryRun, &report)); err != nil {
+					report.abort(filePath, err, clireport.EXIT_FAILED)
+					return
+				}
+			}
+			return
+		}
+
+		if specFile != "" {
+			specs, err := readSpecFile(specFile)
+			if err != nil {
+				report.abort(specFile, err, clireport.EXIT_USAGE)
+				return
+			}
 
-	flag.Parse()
-	if flag.NArg() < 1 {
-		flag.Usage()
+			//the unnamed section, if any, becomes -s for the paths given
+			var named []nfs4acl.PathSpec
+			setSpec, named = splitSpecs(specs)
+			if setSpec == "" && len(args) > 0 {
+				clireport.UsageError(rootCmd, fmt.Sprintf("%s: no acl for the paths given, only \"# file:\" sections", specFile))
+			} else if setSpec != "" && len(args) < 1 {
+				clireport.UsageError(rootCmd, fmt.Sprintf("%s: the acl outside \"# file:\" sections needs paths to apply to", specFile))
+			}
+
+			if err = applySpecs(named, dryRun, &report); err != nil {
+				report.abort(specFile, err, clireport.EXIT_FAILED)
+				return
+			}
+			if setSpec == "" {
+				return
+			}
+		}
+
+		var mutate nfs4acl.MutateFunc
+		var err error
+		switch {
+		case addSpec != "":
+			mutate, err = addOp(addSpec)
+		case deleteSpec != "":
+			mutate, err = deleteOp(deleteSpec)
+		case modifySpec != "":
+			if len(args) < 1 {
+				clireport.UsageError(rootCmd, "-m requires the replacement ACE as the first argument")
+			}
+			mutate, err = modifyOp(modifySpec, args[0])
+			args = args[1:]
+		case setSpec != "":
+			mutate, err = setOp(setSpec)
+		}
+		if err != nil {
+			report.abort("", err, clireport.EXIT_USAGE)
+			return
+		}
+
+		if len(args) < 1 {
+			clireport.UsageError(rootCmd, "no paths given")
+		}
+
+		walkOpts := nfs4acl.WalkOptions{
+			FollowSymlinks: logical,
+			Retry:          nfs4acl.DefaultRetryPolicy(),
+		}
+
+		for _, filePath := range args {
+			if prompt.stopped() {
+				break
+			}
+			if (dryRun || prompt != nil) && recursive {
+				err = nfs4acl.WalkACLs(filePath, walkOpts, func(path string, info fs.FileInfo, acl *nfs4acl.ACL, err error) error {
+					if err != nil {
+						return report.record(path, err)
+					}
+					report.scanned()
+					if dryRun {
+						return report.record(path, previewPath(path, acl, mutate, &report))
+					}
+					if err = report.record(path, applyACL(path, acl, mutate, &report)); err == nil && prompt.stopped() {
+						return filepath.SkipAll
+					}
+					return err
+				})
+			} else if dryRun {
+				err = report.record(filePath, previewFile(filePath, mutate, &report))
+			} else if recursive {
+				err = nfs4acl.ApplyRecursive(filePath, mutate, nfs4acl.WithWalkOptions(walkOpts),
+					nfs4acl.WithRetryPolicy(nfs4acl.DefaultRetryPolicy()),
+					nfs4acl.WithErrorHandler(report.record),
+					nfs4acl.WithResultHandler(report.result),
+					nfs4acl.WithProgress(report.progress(), 0))
+			} else {
+				err = report.record(filePath, applyPath(filePath, mutate, &report))
+			}
+			if err != nil {
+				report.abort(filePath, err, clireport.EXIT_FAILED)
+				return
+			}
+		}
 	}
 
-	for i := 0; i < flag.NArg(); i++ {
-		filePath := flag.Arg(i)
-		acl, err := nfs4acl.Nfs4_getacl_for_path(filePath)
-		acl.RemoveMask(NFS4_ACE_WRITE_DATA)
-		err = nfs4acl.Nfs4_setacl_for_path(filePath, acl)
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(clireport.EXIT_USAGE)
+	}
+}
+
+//Resets filePath to the ACL inherited from its parent and, when recursive,
+//propagates that ACL on to everything below it
+func resetPath(filePath string, recursive, dryRun bool, report *runReport) error {
+	var zfs []nfs4acl.Option
+	if report.zfs {
+		zfs = append(zfs, nfs4acl.WithZFS())
+	}
+	inherited, err := nfs4acl.InheritedACLForPath(filePath, zfs...)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		diff, err := nfs4acl.PreviewSet(filePath, inherited)
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+		report.scanned()
+		printPreview(filePath, inherited, diff, report)
+		return nil
+	}
+	if prompt != nil {
+		current, err := nfs4acl.GetACL(filePath)
+		if err != nil {
+			return err
+		}
+		if !prompt.confirm(filePath, current, inherited) {
+			report.scanned()
+			report.result(filePath, current, false)
+			return nil
+		}
+	}
+	if err = nfs4acl.ResetToInherited(filePath, zfs...); err != nil {
+		return err
+	}
+	report.scanned()
+	report.result(filePath, inherited, true)
+	if !recursive {
+		return nil
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil || !info.IsDir() {
+		return err
+	}
+	return nfs4acl.PropagateInheritance(filePath, append(zfs, nfs4acl.WithErrorHandler(report.record),
+		nfs4acl.WithRetryPolicy(nfs4acl.DefaultRetryPolicy()),
+		nfs4acl.WithResultHandler(report.result),
+		nfs4acl.WithProgress(report.progress(), 0))...)
+}
+
+//Reads and parses a spec file, "-" meaning stdin
+func readSpecFile(specFile string) ([]nfs4acl.PathSpec, error) {
+	var r io.Reader = os.Stdin
+	if specFile != "-" {
+		f, err := os.Open(specFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	specs, err := nfs4acl.ParseSpecFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", specFile, err)
+	}
+	return specs, nil
+}
+
+//Fetches the ACL of filePath and previews mutate against it
+func previewFile(filePath string, mutate nfs4acl.MutateFunc, report *runReport) error {
+	acl, err := nfs4acl.GetACL(filePath)
+	if err != nil {
+		return err
+	}
+	report.scanned()
+
+	return previewPath(filePath, acl, mutate, report)
+}
+
+//Prints the ACL mutate would produce for filePath, and how it differs from
+//the current one, without writing anything. An ACL the mount wouldn't take
+//fails as the write would
+func previewPath(filePath string, acl *nfs4acl.ACL, mutate nfs4acl.MutateFunc, report *runReport) error {
+	updated := acl.Clone()
+	if _, err := mutate(updated); err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	if err := nfs4acl.CheckFits(filePath, updated); err != nil {
+		return err
+	}
+
+	printPreview(filePath, updated, nfs4acl.DiffACLs(acl, updated), report)
+	return nil
+}
+
+//Prints the ACL filePath would get and the changes that takes
+func printPreview(filePath string, updated *nfs4acl.ACL, diff *nfs4acl.Diff, report *runReport) {
+	report.result(filePath, updated, diff.Changed())
+	if report.jsonl != nil {
+		return
+	}
+
+	fmt.Printf("# file: %s\n", filePath)
+	updated.PrintACL(false)
+	if diff.Changed() {
+		fmt.Println("# changes:")
+		fmt.Print(diff)
+	}
+	fmt.Println()
+}
+
+//Times a single path is edited again when the ACL changed under the edit
+const CONFLICT_RETRIES = 3
+
+//Returned by the ModifyACL callback of applyPath when -i declines the change
+var errDeclined = errors.New("change declined")
+
+//Applies mutate to a single path. The write is dropped, and the edit redone,
+//if the ACL changes meanwhile. With -i the change is only written once
+//confirmed
+func applyPath(filePath string, mutate nfs4acl.MutateFunc, report *runReport) error {
+	var result *nfs4acl.ACL
+	changed, scanned := false, false
+	err := nfs4acl.ModifyACL(filePath, func(acl *nfs4acl.ACL) error {
+		if !scanned {
+			report.scanned()
+			scanned = true
+		}
+		before := acl.Clone()
+		if _, err := mutate(acl); err != nil {
+			return fmt.Errorf("%s: %w", filePath, err)
+		}
+		changed = acl.Hash() != before.Hash()
+		if changed && prompt != nil && !prompt.confirm(filePath, before, acl) {
+			changed, result = false, before
+			return errDeclined
+		}
+		result = acl
+		return nil
+	}, nfs4acl.WithConflictRetries(CONFLICT_RETRIES))
+	if err != nil && !errors.Is(err, errDeclined) {
+		return err
+	}
+
+	report.result(filePath, result, changed)
+	return nil
+}
+
+//Applies mutate to acl, just fetched from filePath, and writes it back.
+//With -i the change is only written once confirmed
+func applyACL(filePath string, acl *nfs4acl.ACL, mutate nfs4acl.MutateFunc, report *runReport) error {
+	var before *nfs4acl.ACL
+	if prompt != nil {
+		before = acl.Clone()
+	}
+
+	changed, err := mutate(acl)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	if changed && prompt != nil && !prompt.confirm(filePath, before, acl) {
+		changed = false
+		acl = before
+	}
+	if changed {
+		if err = nfs4acl.SetACL(filePath, acl); err != nil {
+			return err
 		}
 	}
+	report.result(filePath, acl, changed)
+	return nil
 }
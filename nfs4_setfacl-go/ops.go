@@ -0,0 +1,145 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"strconv"
+	"strings"
+)
+
+//Builds the mutation for -a. The ACEs are inserted consecutively, starting at
+//the 1-based index that may follow the last ACE, or at the top of the ACL
+func addOp(spec string) (nfs4acl.MutateFunc, error) {
+	entries := nfs4acl.SplitACLSpec(spec)
+	index := 1
+	if n := len(entries); n > 0 {
+		fields := strings.Split(entries[n-1], ":")
+		if len(fields) == 5 {
+			i, err := strconv.Atoi(fields[4])
+			if err != nil || i < 1 {
+				return nil, fmt.Errorf("invalid ace index %q", fields[4])
+			}
+			index = i
+			entries[n-1] = strings.Join(fields[:4], ":")
+		}
+	}
+	aceSpec := strings.Join(entries, ",")
+
+	//catch spec errors before touching any file
+	if _, err := nfs4acl.ParseACLSpec(aceSpec, false); err != nil {
+		return nil, err
+	}
+
+	return func(acl *nfs4acl.ACL) (bool, error) {
+		aces, err := acl.ParseACEs(aceSpec)
+		if err != nil {
+			return false, err
+		}
+		if index > acl.Len()+1 {
+			return false, fmt.Errorf("ace index %d out of range, acl has %d entries", index, acl.Len())
+		}
+
+		for i, ace := range aces {
+			if err = acl.InsertACE(index-1+i, ace); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}, nil
+}
+
+//Builds the mutation for -x, which takes either a 1-based index or a list of
+//ACEs. Every ACE equal to one in the list is removed
+func deleteOp(spec string) (nfs4acl.MutateFunc, error) {
+	if index, err := strconv.Atoi(spec); err == nil {
+		return func(acl *nfs4acl.ACL) (bool, error) {
+			if err := acl.RemoveACE(index - 1); err != nil {
+				return false, fmt.Errorf("ace index %d out of range, acl has %d entries", index, acl.Len())
+			}
+			return true, nil
+		}, nil
+	}
+
+	if _, err := nfs4acl.ParseACLSpec(spec, false); err != nil {
+		return nil, err
+	}
+
+	return func(acl *nfs4acl.ACL) (bool, error) {
+		aces, err := acl.ParseACEs(spec)
+		if err != nil {
+			return false, err
+		}
+
+		for _, ace := range aces {
+			i := acl.IndexOf(ace)
+			if i < 0 {
+				return false, fmt.Errorf("ace %s not found", ace)
+			}
+			for ; i >= 0; i = acl.IndexOf(ace) {
+				acl.RemoveACE(i)
+			}
+		}
+		return true, nil
+	}, nil
+}
+
+//Builds the mutation for -m, replacing every ACE equal to oldSpec
+func modifyOp(oldSpec, newSpec string) (nfs4acl.MutateFunc, error) {
+	if _, err := nfs4acl.ParseACE(oldSpec, false); err != nil {
+		return nil, err
+	}
+	if _, err := nfs4acl.ParseACE(newSpec, false); err != nil {
+		return nil, err
+	}
+
+	return func(acl *nfs4acl.ACL) (bool, error) {
+		oldAces, err := acl.ParseACEs(oldSpec)
+		if err != nil {
+			return false, err
+		}
+		newAces, err := acl.ParseACEs(newSpec)
+		if err != nil {
+			return false, err
+		}
+
+		found := false
+		for i, ace := range acl.ACEs() {
+			if ace.Equal(oldAces[0]) {
+				acl.ReplaceACE(i, newAces[0])
+				found = true
+			}
+		}
+		if !found {
+			return false, fmt.Errorf("ace %s not found", oldAces[0])
+		}
+		return true, nil
+	}, nil
+}
+
+//Builds the mutation for -s, replacing the whole ACL
+func setOp(spec string) (nfs4acl.MutateFunc, error) {
+	if _, err := nfs4acl.ParseACLSpec(spec, false); err != nil {
+		return nil, err
+	}
+
+	return func(acl *nfs4acl.ACL) (bool, error) {
+		aces, err := acl.ParseACEs(spec)
+		if err != nil {
+			return false, err
+		}
+		return replaceOp(aces)(acl)
+	}, nil
+}
+
+//Builds a mutation replacing the whole ACL with aces
+func replaceOp(aces []*nfs4acl.ACE) nfs4acl.MutateFunc {
+	return func(acl *nfs4acl.ACL) (bool, error) {
+		acl.ClearACEs()
+		for _, ace := range aces {
+			acl.AppendACE(ace)
+		}
+		return true, nil
+	}
+}
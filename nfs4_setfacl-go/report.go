@@ -0,0 +1,167 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/internal/clireport"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"log"
+	"sync"
+)
+
+//End of run summary, also written by --summary-json
+type runSummary struct {
+	clireport.Summary
+	Changed      int64 `json:"changed"`       //or would change, with --test
+	SkippedStale int64 `json:"skipped_stale"` //removed or replaced meanwhile
+}
+
+//Outcomes of a path as streamed by --jsonl
+const (
+	RESULT_CHANGED      = "changed"
+	RESULT_WOULD_CHANGE = "would_change" //with --test
+	RESULT_UNCHANGED    = "unchanged"
+	RESULT_SKIPPED      = "skipped" //replaced meanwhile, or no NFSv4 ACL support with --skip-errors
+	RESULT_ERROR        = "error"
+)
+
+//One line of --jsonl output
+type pathResult struct {
+	Path   string       `json:"path"`
+	Result string       `json:"result"`
+	ACL    *nfs4acl.ACL `json:"acl,omitempty"` //the ACL the path now has
+	Error  string       `json:"error,omitempty"`
+}
+
+//Collects per-path results. With skipErrors failures are logged and the run
+//goes on, and paths on filesystems without NFSv4 ACLs are skipped quietly;
+//otherwise the first failure is handed back to abort the run
+type runReport struct {
+	skipErrors bool
+	dryRun     bool
+	zfs        bool          //warn of what the ZFS dataset will do to changed ACLs
+	jsonl      *json.Encoder //set to stream every result
+
+	mu      sync.Mutex
+	summary runSummary
+}
+
+//Counts a path whose ACL was read
+func (r *runReport) scanned() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Scanned++
+}
+
+//Records a path processed successfully, acl being the ACL it now has or,
+//with --test, would have
+func (r *runReport) result(path string, acl *nfs4acl.ACL, changed bool) {
+	if r.zfs && changed {
+		//runs zfs get, so not under mu
+		warnings, err := nfs4acl.CheckZFS(path, acl)
+		if err != nil {
+			log.Print(err)
+		}
+		for _, warning := range warnings {
+			log.Printf("%s: %s", path, warning)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := RESULT_UNCHANGED
+	if changed {
+		r.summary.Changed++
+		result = RESULT_CHANGED
+		if r.dryRun {
+			result = RESULT_WOULD_CHANGE
+		}
+	}
+	r.emit(pathResult{Path: path, Result: result, ACL: acl})
+}
+
+//Returns a ProgressFunc for one bulk operation, counting the paths it scans.
+//Snapshots are cumulative, so only what's new since the last one is added.
+//Its changes are counted through result
+func (r *runReport) progress() nfs4acl.ProgressFunc {
+	var reported int64
+	return func(p nfs4acl.Progress) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.summary.Scanned += p.Scanned - reported
+		reported = p.Scanned
+	}
+}
+
+//Writes a line of --jsonl output. Called with mu held, so lines never
+//interleave
+func (r *runReport) emit(line pathResult) {
+	if r.jsonl == nil {
+		return
+	}
+	if err := r.jsonl.Encode(line); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//Records the failure of path, if any. Returns nil when the run should
+//continue, the error to end it with otherwise
+func (r *runReport) record(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	//the file changed under us, which is no failure of the run
+	if nfs4acl.IsStaleSkip(err) {
+		log.Print(err)
+		r.summary.SkippedStale++
+		r.emit(pathResult{Path: path, Result: RESULT_SKIPPED, Error: err.Error()})
+		return nil
+	}
+	if r.skipErrors && nfs4acl.IsNotSupported(err) {
+		r.summary.SkippedUnsupported++
+		r.emit(pathResult{Path: path, Result: RESULT_SKIPPED, Error: err.Error()})
+		return nil
+	}
+	r.emit(pathResult{Path: path, Result: RESULT_ERROR, Error: err.Error()})
+	err = r.summary.Fail(path, err)
+	if !r.skipErrors {
+		return err
+	}
+	log.Print(err)
+	return nil
+}
+
+//Ends the run early with err, exiting with code once the summary is out.
+//path is what err is about, if anything
+func (r *runReport) abort(path string, err error, code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Abort(path, err, code)
+}
+
+//Prints the summary to stderr
+func (r *runReport) print() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Print(fmt.Sprintf("scanned: %d, changed: %d, skipped (unsupported): %d, skipped (stale): %d, errors: %d",
+		r.summary.Scanned, r.summary.Changed, r.summary.SkippedUnsupported, r.summary.SkippedStale, len(r.summary.Errors)))
+}
+
+//Writes the summary as JSON to file, "-" meaning stdout
+func (r *runReport) writeJSON(file string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return clireport.WriteJSON(file, r.summary)
+}
+
+//Exit code for the run so far
+func (r *runReport) exitCode() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.summary.ExitCode()
+}
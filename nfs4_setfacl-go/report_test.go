@@ -0,0 +1,26 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"github.com/cclose/libnfs4acl-go/v2"
+	"testing"
+)
+
+func TestProgressCountsOnce(t *testing.T) {
+	var report runReport
+	report.scanned()
+
+	//snapshots are cumulative, whatever the interval
+	first := report.progress()
+	for _, scanned := range []int64{3, 7, 10, 10} {
+		first(nfs4acl.Progress{Scanned: scanned})
+	}
+	second := report.progress()
+	second(nfs4acl.Progress{Scanned: 2})
+	second(nfs4acl.Progress{Scanned: 5})
+
+	if got := report.summary.Scanned; got != 16 {
+		t.Errorf("scanned %d, want 16", got)
+	}
+}
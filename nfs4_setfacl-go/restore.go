@@ -0,0 +1,59 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/v2"
+)
+
+//Reapplies every ACL recorded in the dump or spec file at restoreFile. Every
+//section must name its path
+func restore(restoreFile string, dryRun bool, report *runReport) error {
+	specs, err := readSpecFile(restoreFile)
+	if err != nil {
+		return err
+	}
+
+	unnamed, named := splitSpecs(specs)
+	if unnamed != "" {
+		return fmt.Errorf("%s: ace before any \"# file:\" header", restoreFile)
+	}
+	return applySpecs(named, dryRun, report)
+}
+
+//Separates the unnamed section of a spec file from the "# file:" sections
+func splitSpecs(specs []nfs4acl.PathSpec) (unnamed string, named []nfs4acl.PathSpec) {
+	for _, spec := range specs {
+		if spec.Path == "" {
+			unnamed = spec.Spec
+		} else {
+			named = append(named, spec)
+		}
+	}
+	return unnamed, named
+}
+
+//Sets the ACL of the path of every section
+func applySpecs(specs []nfs4acl.PathSpec, dryRun bool, report *runReport) error {
+	for _, spec := range specs {
+		if prompt.stopped() {
+			break
+		}
+		mutate, err := setOp(spec.Spec)
+		if err != nil {
+			return fmt.Errorf("%s: %v", spec.Path, err)
+		}
+
+		if dryRun {
+			err = previewFile(spec.Path, mutate, report)
+		} else {
+			err = applyPath(spec.Path, mutate, report)
+		}
+		if err = report.record(spec.Path, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	//"unsafe"
+)
+
+const NFS4_ACL_XATTR = "system.nfs4_acl"
+const ERROR_NFS4_NOT_SUPPORTED = "operation not supported"
+const XATTR_REPLACE_FLAG = 0x2
+
+//Reads the ACL of path, detecting whether it's a directory
+func GetACL(path string, opts ...Option) (acl *ACL, err error) {
+	//Validate the Path and detect directory
+	fi, err := os.Stat(path)
+	if err != nil {
+		//File Not Exists and other errors
+		return
+	} //implicit else
+	isDir := fi.IsDir() //detect if the path is a directory
+
+	return LoadACL(path, isDir, opts...)
+}
+
+//Proxy function that can be used when you already know your path exists and
+//if the path is a directory or not. this is helpful when using filepath walks.
+//If the ACL grows between sizing and fetching it, the fetch is retried, see
+//WithRangeRetries and WithXattrSlack. Transient errors are retried as
+//WithRetryPolicy sets
+func LoadACL(path string, isDir bool, opts ...Option) (acl *ACL, err error) {
+	o := newOptions(opts)
+	fetch := func(value []byte) (result int, err error) {
+		err = o.retry.do(context.Background(), path, nil, o.log(), func() error {
+			result, err = nfs4_getxattr(path, value)
+			return err
+		})
+		return
+	}
+
+	xattr := getXattrBuffer()
+	defer putXattrBuffer(xattr)
+	defer func() {
+		if err != nil {
+			currentMetrics().Error(ErrorClass(err))
+		}
+	}()
+
+	//the pooled buffer usually fits already, sparing the size probe
+	result, err := fetch(*xattr)
+	for attempt := 0; err != nil; attempt++ {
+		if !errors.Is(err, syscall.ERANGE) || attempt > o.rangeRetries {
+			return
+		}
+		if attempt > 0 {
+			o.log().Debugf("nfs4acl: %s: acl changed size while reading, retrying", path)
+		}
+
+		//get the size of our value buffer
+		result, err = fetch(nil)
+		if err != nil {
+			return
+		}
+
+		*xattr = growBuffer(*xattr, result+o.xattrSlack)
+		result, err = fetch(*xattr)
+	}
+
+	acl, err = XAttrLoad((*xattr)[:result], isDir)
+	if err != nil {
+		err = fmt.Errorf("decode %s of %s: %w", NFS4_ACL_XATTR, path, err)
+		return
+	}
+	currentMetrics().ACLRead(result)
+
+	//return acl, err
+	return
+
+}
+
+//Replaces the ACL of path with acl. An ACL too large for the mount, see
+//CheckFits, is refused before writing. Transient errors are retried as
+//WithRetryPolicy sets
+func SetACL(path string, acl *ACL, opts ...Option) (err error) {
+	return SetACLContext(context.Background(), path, acl, opts...)
+}
+
+//Same as SetACL, ctx carries audit metadata (see WithAuditMetadata) and cuts
+//retries short
+func SetACLContext(ctx context.Context, path string, acl *ACL, opts ...Option) (err error) {
+	o := newOptions(opts)
+	return auditedWrite(ctx, path, acl, func() error {
+		if err := CheckFits(path, acl); err != nil {
+			currentMetrics().Error(ErrorClass(err))
+			return err
+		}
+		return o.retry.do(ctx, path, o.expect, o.log(), func() error {
+			return nfs4_setxattr(path, acl)
+		})
+	})
+}
+
+//Errors are wrapped with the operation, attribute and path, the errno stays
+//reachable through errors.Is and errors.As
+func nfs4_getxattr(path string, value []byte) (int, error) {
+	result, err := getxattr(path, NFS4_ACL_XATTR, value)
+	if err != nil {
+		return result, fmt.Errorf("getxattr %s %s: %w", NFS4_ACL_XATTR, path, err)
+	}
+
+	return result, nil
+}
+
+func nfs4_setxattr(path string, acl *ACL) error {
+	if err := acl.checkPack(); err != nil {
+		currentMetrics().Error(ErrorClass(err))
+		return fmt.Errorf("encode %s for %s: %w", NFS4_ACL_XATTR, path, err)
+	}
+
+	//the kernel copies the value, so the buffer can go straight back
+	buf := getXattrBuffer()
+	defer putXattrBuffer(buf)
+	*buf = acl.packTo(*buf)
+
+	if err := setxattr(path, NFS4_ACL_XATTR, *buf, XATTR_REPLACE_FLAG); err != nil {
+		currentMetrics().Error(ErrorClass(err))
+		return fmt.Errorf("setxattr %s %s: %w", NFS4_ACL_XATTR, path, err)
+	}
+	currentMetrics().ACLWritten(len(*buf))
+	return nil
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+//Principal is the identity whose access is evaluated, together with the
+//ownership of the file, which OWNER@ and GROUP@ are resolved against
+type Principal struct {
+	Name   string   //user name, with or without the NFSv4 domain
+	UID    uint32   //matched against numeric whos and the file owner
+	Groups []string //group names, with or without the NFSv4 domain
+	GIDs   []uint32 //matched against numeric group whos and the file group
+
+	FileUID uint32 //owner of the file the ACL belongs to
+	FileGID uint32 //owning group of the file the ACL belongs to
+
+	//Anonymous principals match ANONYMOUS@, all others AUTHENTICATED@
+	Anonymous bool
+	//How the principal is logged in, matched against INTERACTIVE@, NETWORK@,
+	//DIALUP@, BATCH@ and SERVICE@. The server decides these, so they only
+	//apply when listed here, e.g. []uint{NFS4_ACL_WHO_NETWORK}
+	Sessions []uint
+
+	//NFSv4 domain of the local accounts, as in idmapd.conf. Whos that match
+	//none of the names above are looked up locally only when they have no
+	//domain or this one, so bob@other.org is never taken for the local bob
+	Domain string
+}
+
+//Evaluates the ACL the way an NFSv4 server does (RFC 7530 section 6.2.1):
+//ACEs are processed in order, skipping inherit-only ACEs and ACEs that don't
+//apply to the principal. The first ACE to mention a requested bit decides it:
+//a deny rejects the whole request, and the request is granted once every bit
+//has been allowed. decidingACE is a copy of the ACE that settled the outcome,
+//or nil if the end of the ACL was reached with bits still undecided
+func CheckAccess(acl *ACL, principal Principal, requested AccessMask) (granted bool, decidingACE *ACE, err error) {
+	if acl == nil {
+		return false, nil, errors.New("nil acl")
+	}
+	if requested == 0 {
+		return false, nil, errors.New("empty access request")
+	}
+
+	undecided := requested
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		if ace.Flags&NFS4_ACE_INHERIT_ONLY_ACE != 0 || !principal.appliesTo(ace) {
+			continue
+		}
+		if ace.AccessMask&undecided == 0 {
+			continue
+		}
+
+		aceCopy := *ace
+		switch ace.AceType {
+		case NFS4_ACE_ACCESS_DENIED_ACE_TYPE:
+			return false, &aceCopy, nil
+		case NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE:
+			undecided &^= ace.AccessMask
+			if undecided == 0 {
+				return true, &aceCopy, nil
+			}
+		}
+	}
+
+	return false, nil, nil
+}
+
+//Reports whether the ACE's who names the principal
+func (p Principal) appliesTo(ace *ACE) bool {
+	switch ace.WhoType {
+	case NFS4_ACL_WHO_OWNER:
+		return p.UID == p.FileUID
+	case NFS4_ACL_WHO_GROUP:
+		return p.hasGID(p.FileGID)
+	case NFS4_ACL_WHO_EVERYONE:
+		return true
+	case NFS4_ACL_WHO_ANONYMOUS:
+		return p.Anonymous
+	case NFS4_ACL_WHO_AUTHENTICATED:
+		return !p.Anonymous
+	case NFS4_ACL_WHO_INTERACTIVE, NFS4_ACL_WHO_NETWORK, NFS4_ACL_WHO_DIALUP,
+		NFS4_ACL_WHO_BATCH, NFS4_ACL_WHO_SERVICE:
+		for _, session := range p.Sessions {
+			if session == ace.WhoType {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ace.Flags&NFS4_ACE_IDENTIFIER_GROUP != 0 {
+		for _, group := range p.Groups {
+			if samePrincipalName(ace.Who, group) {
+				return true
+			}
+		}
+		//servers without idmapping send numeric whos
+		if gid, err := strconv.ParseUint(ace.Who, 10, 32); err == nil {
+			return p.hasGID(uint32(gid))
+		}
+		//the principal may only be known by its gids
+		name, ok := p.localName(ace.Who)
+		if !ok {
+			return false
+		}
+		gid, err := GetPrincipalResolver().LookupGroup(name)
+		return err == nil && p.hasGID(gid)
+	}
+
+	if samePrincipalName(ace.Who, p.Name) {
+		return true
+	}
+	if uid, err := strconv.ParseUint(ace.Who, 10, 32); err == nil {
+		return uint32(uid) == p.UID
+	}
+	//the principal may only be known by its uid
+	name, ok := p.localName(ace.Who)
+	if !ok {
+		return false
+	}
+	uid, err := GetPrincipalResolver().LookupUser(name)
+	return err == nil && uid == p.UID
+}
+
+//Returns the local account name of who, which is who itself when it has no
+//domain and who without its domain when that is the principal's. ok is false
+//for whos of other domains, which local accounts can't be
+func (p Principal) localName(who string) (name string, ok bool) {
+	at := strings.LastIndex(who, "@")
+	if at < 0 {
+		return who, true
+	}
+	if p.Domain == "" || !strings.EqualFold(who[at+1:], p.Domain) {
+		return "", false
+	}
+	return who[:at], true
+}
+
+//Drops the NFSv4 domain from a who
+func bareWho(who string) string {
+	if at := strings.LastIndex(who, "@"); at >= 0 {
+		return who[:at]
+	}
+	return who
+}
+
+func (p Principal) hasGID(gid uint32) bool {
+	for _, cur := range p.GIDs {
+		if cur == gid {
+			return true
+		}
+	}
+	return false
+}
+
+//Compares principal names, ignoring the domain when only one side has one
+func samePrincipalName(who, name string) bool {
+	if who == name {
+		return true
+	}
+	whoAt, nameAt := strings.LastIndex(who, "@"), strings.LastIndex(name, "@")
+	if name == "" || (whoAt >= 0 && nameAt >= 0) {
+		return false
+	}
+
+	if whoAt >= 0 {
+		who = who[:whoAt]
+	}
+	if nameAt >= 0 {
+		name = name[:nameAt]
+	}
+	return who == name
+}
+
+//Every distinct access mask bit, in permission letter order
+var accessMaskBits = []AccessMask{
+	NFS4_ACE_READ_DATA,
+	NFS4_ACE_WRITE_DATA,
+	NFS4_ACE_APPEND_DATA,
+	NFS4_ACE_DELETE_CHILD,
+	NFS4_ACE_DELETE,
+	NFS4_ACE_EXECUTE,
+	NFS4_ACE_READ_ATTRIBUTES,
+	NFS4_ACE_WRITE_ATTRIBUTES,
+	NFS4_ACE_READ_NAMED_ATTRS,
+	NFS4_ACE_WRITE_NAMED_ATTRS,
+	NFS4_ACE_READ_ACL,
+	NFS4_ACE_WRITE_ACL,
+	NFS4_ACE_WRITE_OWNER,
+	NFS4_ACE_SYNCHRONIZE,
+}
+
+//BitDecision explains how a single access mask bit was decided
+type BitDecision struct {
+	Bit     AccessMask
+	Granted bool
+	ACE     *ACE //copy of the first applicable ACE mentioning Bit, nil if none did
+}
+
+//Computes the net access mask the principal holds: every bit allowed by an
+//applicable ACE before any applicable ACE denied it
+func EffectiveAccess(acl *ACL, principal Principal) AccessMask {
+	var granted AccessMask
+	for _, decision := range EffectiveAccessExplained(acl, principal) {
+		if decision.Granted {
+			granted |= decision.Bit
+		}
+	}
+
+	return granted
+}
+
+//Same as EffectiveAccess, but returns a decision for every defined access
+//mask bit naming the ACE responsible for it
+func EffectiveAccessExplained(acl *ACL, principal Principal) []BitDecision {
+	deciders := make(map[AccessMask]*ACE)
+	var decided, granted AccessMask
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		if ace.Flags&NFS4_ACE_INHERIT_ONLY_ACE != 0 || !principal.appliesTo(ace) {
+			continue
+		}
+		if ace.AceType != NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE && ace.AceType != NFS4_ACE_ACCESS_DENIED_ACE_TYPE {
+			continue
+		}
+
+		bits := ace.AccessMask &^ decided
+		if bits == 0 {
+			continue
+		}
+		if ace.AceType == NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE {
+			granted |= bits
+		}
+		aceCopy := *ace
+		for _, bit := range accessMaskBits {
+			if bits&bit != 0 {
+				deciders[bit] = &aceCopy
+			}
+		}
+		decided |= bits
+	}
+
+	decisions := make([]BitDecision, len(accessMaskBits))
+	for i, bit := range accessMaskBits {
+		decisions[i] = BitDecision{
+			Bit:     bit,
+			Granted: granted&bit != 0,
+			ACE:     deciders[bit],
+		}
+	}
+
+	return decisions
+}
+
+//Reports whether a and b grant every principal the same effective access,
+//however their ACEs are ordered, split or merged. Only access to the object
+//itself is compared: inherit-only ACEs and audit and alarm ACEs are ignored,
+//so the ACLs may still pass different entries on to new children
+func EquivalentAccess(a, b *ACL, principals []Principal) bool {
+	for _, principal := range principals {
+		if EffectiveAccess(a, principal) != EffectiveAccess(b, principal) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl_test
+
+import (
+	"errors"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/cclose/libnfs4acl-go/v2/nfs4acltest"
+	"testing"
+)
+
+func TestCheckAccess(t *testing.T) {
+	acl := nfs4acltest.ACL(t, "D::bob@example.com:w,"+
+		"A::OWNER@:rwx,"+
+		"A:g:GROUP@:rx,"+
+		"A:i:EVERYONE@:w,"+
+		"A::EVERYONE@:r", false)
+	owner := nfs4acl.Principal{Name: "alice@example.com", UID: 1000, FileUID: 1000, FileGID: 100}
+	member := nfs4acl.Principal{Name: "carol", UID: 1001, GIDs: []uint32{100}, FileUID: 1000, FileGID: 100}
+	bob := nfs4acl.Principal{Name: "bob@example.com", UID: 1002, FileUID: 1000, FileGID: 100}
+	other := nfs4acl.Principal{Name: "dave", UID: 1003, FileUID: 1000, FileGID: 100}
+
+	for _, test := range []struct {
+		name      string
+		principal nfs4acl.Principal
+		requested nfs4acl.AccessMask
+		granted   bool
+		deciding  string
+	}{
+		{"owner writes", owner, nfs4acl.NFS4_ACE_WRITE_DATA, true, "A::OWNER@:rwx"},
+		{"member executes", member, nfs4acl.NFS4_ACE_EXECUTE, true, "A:g:GROUP@:rx"},
+		{"member writes", member, nfs4acl.NFS4_ACE_WRITE_DATA, false, ""},
+		{"deny comes first", bob, nfs4acl.NFS4_ACE_WRITE_DATA, false, "D::bob@example.com:w"},
+		{"deny only for its bits", bob, nfs4acl.NFS4_ACE_READ_DATA, true, "A::EVERYONE@:r"},
+		{"inherit only ignored", other, nfs4acl.NFS4_ACE_WRITE_DATA, false, ""},
+		{"all bits needed", other, nfs4acl.NFS4_ACE_READ_DATA | nfs4acl.NFS4_ACE_EXECUTE, false, ""},
+	} {
+		granted, deciding, err := nfs4acl.CheckAccess(acl, test.principal, test.requested)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if granted != test.granted {
+			t.Errorf("%s: granted %t, want %t", test.name, granted, test.granted)
+		}
+		got := ""
+		if deciding != nil {
+			got = deciding.Format(false, false)
+		}
+		if got != test.deciding {
+			t.Errorf("%s: decided by %q, want %q", test.name, got, test.deciding)
+		}
+	}
+}
+
+func TestCheckAccessErrors(t *testing.T) {
+	if _, _, err := nfs4acl.CheckAccess(nil, nfs4acl.Principal{}, nfs4acl.NFS4_ACE_READ_DATA); err == nil {
+		t.Error("nil acl accepted")
+	}
+	acl := nfs4acltest.ACL(t, "A::EVERYONE@:r", false)
+	if _, _, err := nfs4acl.CheckAccess(acl, nfs4acl.Principal{}, 0); err == nil {
+		t.Error("empty request accepted")
+	}
+}
+
+func TestEffectiveAccess(t *testing.T) {
+	acl := nfs4acltest.ACL(t, "D::EVERYONE@:x,A::OWNER@:rwx,A::EVERYONE@:r", false)
+	owner := nfs4acl.Principal{UID: 1000, FileUID: 1000}
+	want := nfs4acl.NFS4_ACE_READ_DATA | nfs4acl.NFS4_ACE_WRITE_DATA
+	if got := nfs4acl.EffectiveAccess(acl, owner); got != want {
+		t.Errorf("effective %s, want %s", nfs4acl.PermsString(got, false), nfs4acl.PermsString(want, false))
+	}
+}
+
+func TestEquivalentAccess(t *testing.T) {
+	principals := []nfs4acl.Principal{
+		{UID: 1000, FileUID: 1000, FileGID: 100},
+		{UID: 1001, GIDs: []uint32{100}, FileUID: 1000, FileGID: 100},
+		{UID: 1002, FileUID: 1000, FileGID: 100},
+	}
+	a := nfs4acltest.ACL(t, "A::OWNER@:rw,A:g:GROUP@:r,A::EVERYONE@:r", false)
+	//same outcome for everyone, written differently
+	b := nfs4acltest.ACL(t, "A::EVERYONE@:r,A::OWNER@:w", false)
+	if !nfs4acl.EquivalentAccess(a, b, principals) {
+		t.Error("equivalent acls reported different")
+	}
+	c := nfs4acltest.ACL(t, "A::EVERYONE@:r", false)
+	if nfs4acl.EquivalentAccess(a, c, principals) {
+		t.Error("different acls reported equivalent")
+	}
+}
+
+//Resolves the names in the map, nothing else
+type fakeResolver map[string]uint32
+
+func (r fakeResolver) LookupUser(name string) (uint32, error) {
+	if id, ok := r[name]; ok {
+		return id, nil
+	}
+	return 0, errors.New("unknown " + name)
+}
+
+func (r fakeResolver) LookupGroup(name string) (uint32, error) { return r.LookupUser(name) }
+func (r fakeResolver) UserName(uid uint32) (string, error)     { return "", errors.New("unused") }
+func (r fakeResolver) GroupName(gid uint32) (string, error)    { return "", errors.New("unused") }
+
+func TestEffectiveAccessForeignDomain(t *testing.T) {
+	nfs4acl.SetPrincipalResolver(fakeResolver{"bob": 1002, "staff": 50})
+	t.Cleanup(func() { nfs4acl.SetPrincipalResolver(nil) })
+
+	acl := nfs4acltest.ACL(t, "A::bob@other.org:r,"+
+		"A:g:staff@other.org:a,"+
+		"A::bob@example.com:w,"+
+		"A:g:staff@EXAMPLE.COM:x,"+
+		"A::bob:t", false)
+	//only known by uid and gid, so every named ACE goes through the resolver
+	robert := nfs4acl.Principal{UID: 1002, GIDs: []uint32{50}, Domain: "example.com"}
+
+	want := nfs4acl.NFS4_ACE_WRITE_DATA | nfs4acl.NFS4_ACE_EXECUTE | nfs4acl.NFS4_ACE_READ_ATTRIBUTES
+	if got := nfs4acl.EffectiveAccess(acl, robert); got != want {
+		t.Errorf("effective %s, want %s", nfs4acl.PermsString(got, false), nfs4acl.PermsString(want, false))
+	}
+
+	//without a domain only the bare who is local
+	robert.Domain = ""
+	want = nfs4acl.NFS4_ACE_READ_ATTRIBUTES
+	if got := nfs4acl.EffectiveAccess(acl, robert); got != want {
+		t.Errorf("no domain: effective %s, want %s", nfs4acl.PermsString(got, false), nfs4acl.PermsString(want, false))
+	}
+}
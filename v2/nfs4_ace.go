@@ -0,0 +1,235 @@
+package nfs4acl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+//Static Functions
+func AceGetWhoType(who string) uint {
+	switch who {
+	case NFS4_ACL_WHO_OWNER_STRING:
+		return NFS4_ACL_WHO_OWNER
+	case NFS4_ACL_WHO_GROUP_STRING:
+		return NFS4_ACL_WHO_GROUP
+	case NFS4_ACL_WHO_EVERYONE_STRING:
+		return NFS4_ACL_WHO_EVERYONE
+	case NFS4_ACL_WHO_ANONYMOUS_STRING:
+		return NFS4_ACL_WHO_ANONYMOUS
+	case NFS4_ACL_WHO_AUTHENTICATED_STRING:
+		return NFS4_ACL_WHO_AUTHENTICATED
+	case NFS4_ACL_WHO_INTERACTIVE_STRING:
+		return NFS4_ACL_WHO_INTERACTIVE
+	case NFS4_ACL_WHO_NETWORK_STRING:
+		return NFS4_ACL_WHO_NETWORK
+	case NFS4_ACL_WHO_DIALUP_STRING:
+		return NFS4_ACL_WHO_DIALUP
+	case NFS4_ACL_WHO_BATCH_STRING:
+		return NFS4_ACL_WHO_BATCH
+	case NFS4_ACL_WHO_SERVICE_STRING:
+		return NFS4_ACL_WHO_SERVICE
+	} //implicit default/else
+
+	if IsNumericWho(who) {
+		return NFS4_ACL_WHO_NUMERIC
+	}
+	return NFS4_ACL_WHO_NAMED
+}
+
+//Reports whether who is a plain uid or gid, as used when the server has
+//idmapping disabled (nfs4_disable_idmapping)
+func IsNumericWho(who string) bool {
+	if who == "" {
+		return false
+	}
+	for _, c := range who {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+//Longest who accepted for packing, in bytes. Matches the NFSv4 opaque limit
+//(NFS4_OPAQUE_LIMIT) of the Linux client
+const NFS4_MAX_WHO_LENGTH = 1024
+
+//Checks that who can be stored in the xattr: not empty, valid UTF-8 as NFSv4
+//requires, free of NUL bytes and no longer than NFS4_MAX_WHO_LENGTH. Lengths
+//are counted in bytes, as on the wire, not in characters, so a non-ASCII
+//name may be rejected while having fewer characters than the limit
+func ValidateWho(who string) error {
+	switch {
+	case who == "":
+		return errors.New("empty who")
+	case !utf8.ValidString(who):
+		return fmt.Errorf("who %q is not valid UTF-8", who)
+	case strings.IndexByte(who, 0) >= 0:
+		return fmt.Errorf("who %q contains a NUL byte", who)
+	case len(who) > NFS4_MAX_WHO_LENGTH:
+		return fmt.Errorf("who is %d bytes long, the limit is %d", len(who), NFS4_MAX_WHO_LENGTH)
+	}
+
+	return nil
+}
+
+//Returns the bytes whoLength bytes of who take in the xattr, padded to a
+//whole number of atoms
+func AceWhoStringAtomLength(whoLength int) int {
+	//since the Who string isn't necessarily uint32 sized
+	//we need to find out how many uint32's, rounding up, it used
+	//so we can properly increment the pointer
+	// get the size of our Who string, rounded down to the nears atom
+	whoIncrement := int(whoLength/ATOM_SIZE) * ATOM_SIZE //increment ptr
+	//if these match, then our Who string was uint32 sized
+	if whoIncrement < whoLength {
+		//otherwise, pad it out to the next increment
+		whoIncrement += ATOM_SIZE
+	}
+
+	return whoIncrement
+}
+
+//Struct Declaration
+
+type ACE struct {
+	AceType    AceType
+	WhoType    uint
+	Who        string
+	Flags      AceFlags
+	AccessMask AccessMask
+}
+
+//ACE struct constructor
+func NewACE(aceType AceType, flag AceFlags, mask AccessMask, who string) *ACE {
+	return &ACE{
+		AceType:    aceType,
+		Who:        who,
+		WhoType:    AceGetWhoType(who),
+		Flags:      flag,
+		AccessMask: mask,
+	}
+}
+
+//Every flag bit that goes over the wire
+const nfs4AceWireFlags = NFS4_ACE_FILE_INHERIT_ACE | NFS4_ACE_DIRECTORY_INHERIT_ACE |
+	NFS4_ACE_NO_PROPAGATE_INHERIT_ACE | NFS4_ACE_INHERIT_ONLY_ACE |
+	NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG | NFS4_ACE_FAILED_ACCESS_ACE_FLAG |
+	NFS4_ACE_IDENTIFIER_GROUP | NFS4_ACE_INHERITED_ACE
+
+//ACE constructor for untrusted input: unlike NewACE it rejects
+//unknown ace types, flag and mask bits outside the defined ones, the
+//OWNER@/GROUP@/EVERYONE@ pseudo flags, which are derived from the who, and
+//whos ValidateWho refuses
+func NewACEChecked(aceType AceType, flags AceFlags, mask AccessMask, who string) (*ACE, error) {
+	if aceType > NFS4_ACE_SYSTEM_ALARM_ACE_TYPE {
+		return nil, fmt.Errorf("unknown ace type %d", aceType)
+	}
+	if flags&(NFS4_ACE_OWNER|NFS4_ACE_GROUP|NFS4_ACE_EVERYONE) != 0 {
+		return nil, errors.New("O, G and E flags can't be set directly")
+	}
+	if undefined := flags &^ nfs4AceWireFlags; undefined != 0 {
+		return nil, fmt.Errorf("undefined flag bits %#x", uint32(undefined))
+	}
+	if undefined := mask &^ NFS4_ACE_MASK_ALL; undefined != 0 {
+		return nil, fmt.Errorf("undefined access mask bits %#x", uint32(undefined))
+	}
+	if err := ValidateWho(who); err != nil {
+		return nil, err
+	}
+
+	return NewACE(aceType, flags, mask, who), nil
+}
+
+//Ace methods
+
+//Prints the Ace
+func (ace *ACE) PrintACE(verbose, isDir bool) error {
+	fmt.Println(ace.Format(verbose, isDir))
+	return nil
+}
+
+//Renders the Ace in the type:flags:who:perms form used by nfs4_getfacl
+func (ace *ACE) Format(verbose, isDir bool) string {
+	//Create print buffer
+	var buffer bytes.Buffer
+
+	//Prepare Ace Type
+	buffer.WriteString(AceTypeString(ace.AceType, verbose))
+	buffer.WriteRune(':')
+
+	//Prepare Ace Flags
+	buffer.WriteString(FlagsString(ace.Flags))
+	buffer.WriteRune(':')
+
+	//Prepare Ace WHO
+	buffer.WriteString(ace.Who)
+	buffer.WriteRune(':')
+
+	//Prepare Ace Mask
+	buffer.WriteString(PermsString(ace.AccessMask, isDir))
+
+	return buffer.String()
+}
+
+//Renders the Ace with file semantics, see Format
+func (ace *ACE) String() string {
+	return ace.Format(false, false)
+}
+
+//Reports whether both Aces have the same type, flags, who and access mask
+func (ace *ACE) Equal(other *ACE) bool {
+	return ace.AceType == other.AceType &&
+		ace.Flags == other.Flags &&
+		ace.AccessMask == other.AccessMask &&
+		ace.Who == other.Who
+}
+
+//Bitwise ORs the access mask. This will set any bits in the specified access mask
+//but will not modify any existing set bits
+// value    = 00110101
+// mask     | 00000011
+// result     00110111
+func (ace *ACE) applyAccessMask(accessMask AccessMask) {
+	ace.AccessMask = ace.AccessMask | accessMask
+}
+
+//Bitwise AND NOT the access mask (bit clear). This will unset any bits in the specified access mask
+//but will not modify any others
+// value    = 00110101  mask = 00000011
+// NOT MASK & 11111100
+// result     00110100
+func (ace *ACE) removeAccessMask(accessMask AccessMask) {
+	ace.AccessMask = ace.AccessMask &^ accessMask
+}
+
+//Sets the accessmask to the specified mask. Total overwrite
+func (ace *ACE) setAccessMask(accessMask AccessMask) {
+	ace.AccessMask = accessMask
+}
+
+//Bitwise ORs the flags. This will set any bits in the specified flags
+//but will not modify any existing set bits
+func (ace *ACE) ApplyFlags(flags AceFlags) {
+	ace.Flags = ace.Flags | flags
+}
+
+//Bitwise AND NOT the flags (bit clear). This will unset any bits in the specified flags
+//but will not modify any others
+func (ace *ACE) RemoveFlags(flags AceFlags) {
+	ace.Flags = ace.Flags &^ flags
+}
+
+//Sets the flags to the specified mask. Total overwrite
+func (ace *ACE) SetFlags(flags AceFlags) {
+	ace.Flags = flags
+}
+
+//Reports whether the Ace was inherited from the parent directory rather than
+//set explicitly. Only servers supporting NFSv4.1 ACE4_INHERITED_ACE mark them
+func (ace *ACE) IsInherited() bool {
+	return ace.Flags&NFS4_ACE_INHERITED_ACE != 0
+}
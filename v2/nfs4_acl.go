@@ -0,0 +1,848 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+	//"bytes"
+)
+
+//Size of xattr packing atoms (uint32) in bytes
+const (
+	//This could probably be a constant '4' but i feel safer measuring
+	ATOM_SIZE = int(unsafe.Sizeof(uint32(0)))
+)
+
+//Default ACL Who strings
+const (
+	NFS4_ACL_WHO_OWNER_STRING    = "OWNER@"
+	NFS4_ACL_WHO_GROUP_STRING    = "GROUP@"
+	NFS4_ACL_WHO_EVERYONE_STRING = "EVERYONE@"
+
+	//Further special whos from RFC 7530 section 6.2.1.5
+	NFS4_ACL_WHO_ANONYMOUS_STRING     = "ANONYMOUS@"
+	NFS4_ACL_WHO_AUTHENTICATED_STRING = "AUTHENTICATED@"
+	NFS4_ACL_WHO_INTERACTIVE_STRING   = "INTERACTIVE@"
+	NFS4_ACL_WHO_NETWORK_STRING       = "NETWORK@"
+	NFS4_ACL_WHO_DIALUP_STRING        = "DIALUP@"
+	NFS4_ACL_WHO_BATCH_STRING         = "BATCH@"
+	NFS4_ACL_WHO_SERVICE_STRING       = "SERVICE@"
+)
+
+//ACL Who string enums
+const (
+	NFS4_ACL_WHO_NAMED = iota
+	NFS4_ACL_WHO_OWNER
+	NFS4_ACL_WHO_GROUP
+	NFS4_ACL_WHO_EVERYONE
+	NFS4_ACL_WHO_ANONYMOUS
+	NFS4_ACL_WHO_AUTHENTICATED
+	NFS4_ACL_WHO_INTERACTIVE
+	NFS4_ACL_WHO_NETWORK
+	NFS4_ACL_WHO_DIALUP
+	NFS4_ACL_WHO_BATCH
+	NFS4_ACL_WHO_SERVICE
+	//plain uid or gid, sent by servers with idmapping disabled. Like named
+	//whos, it can't be used with the ByWhoType methods
+	NFS4_ACL_WHO_NUMERIC
+)
+
+//ACE Type enums
+const (
+	NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE AceType = iota
+	NFS4_ACE_ACCESS_DENIED_ACE_TYPE
+	NFS4_ACE_SYSTEM_AUDIT_ACE_TYPE
+	NFS4_ACE_SYSTEM_ALARM_ACE_TYPE
+)
+
+//ACE Type display characters
+const (
+	TYPE_ALLOW = 'A'
+	TYPE_DENY  = 'D'
+	TYPE_AUDIT = 'U'
+	TYPE_ALARM = 'L'
+)
+
+//ACE Flags binary values
+//Each value is the next most significant bit, shifts 1 place left incrementally
+const (
+	NFS4_ACE_FILE_INHERIT_ACE      AceFlags = 1 << iota //0x0001
+	NFS4_ACE_DIRECTORY_INHERIT_ACE                      //0x0010  etc
+	NFS4_ACE_NO_PROPAGATE_INHERIT_ACE
+	NFS4_ACE_INHERIT_ONLY_ACE
+	NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG
+	NFS4_ACE_FAILED_ACCESS_ACE_FLAG
+	NFS4_ACE_IDENTIFIER_GROUP
+	NFS4_ACE_INHERITED_ACE //NFSv4.1, marks ACEs the file got from its parent
+)
+
+//Pseudo flags for OWNER@, GROUP@ and EVERYONE@. They're derived from the who
+//and never go over the wire, PackXAttr leaves them out, so they sit clear of
+//the wire flags, at the values Solaris uses. Breaking change from v1, which had them at 0x80, 0x100
+//and 0x200: 0x80 is INHERITED_ACE now, so flags stored or compared as numbers
+//must be converted
+const (
+	NFS4_ACE_OWNER    AceFlags = 0x00001000
+	NFS4_ACE_GROUP    AceFlags = 0x00002000
+	NFS4_ACE_EVERYONE AceFlags = 0x00004000
+)
+
+//ACE Flags display characters
+const (
+	FLAG_FILE_INHERIT         = 'f'
+	FLAG_DIR_INHERIT          = 'd'
+	FLAG_NO_PROPAGATE_INHERIT = 'n'
+	FLAG_INHERIT_ONLY         = 'i'
+	FLAG_SUCCESSFUL_ACCESS    = 'S'
+	FLAG_FAILED_ACCESS        = 'F'
+	FLAG_GROUP                = 'g'
+	FLAG_INHERITED            = 'I'
+	FLAG_OWNER_AT             = 'O'
+	FLAG_GROUP_AT             = 'G'
+	FLAG_EVERYONE_AT          = 'E'
+)
+
+//ACE access mask values
+//Would use the Iota shift as above, but there's enough duplicated variables here
+const (
+	NFS4_ACE_READ_DATA         AccessMask = 0x00000001
+	NFS4_ACE_LIST_DIRECTORY    AccessMask = 0x00000001
+	NFS4_ACE_WRITE_DATA        AccessMask = 0x00000002
+	NFS4_ACE_ADD_FILE          AccessMask = 0x00000002
+	NFS4_ACE_APPEND_DATA       AccessMask = 0x00000004
+	NFS4_ACE_ADD_SUBDIRECTORY  AccessMask = 0x00000004
+	NFS4_ACE_READ_NAMED_ATTRS  AccessMask = 0x00000008
+	NFS4_ACE_WRITE_NAMED_ATTRS AccessMask = 0x00000010
+	NFS4_ACE_EXECUTE           AccessMask = 0x00000020
+	NFS4_ACE_DELETE_CHILD      AccessMask = 0x00000040
+	NFS4_ACE_READ_ATTRIBUTES   AccessMask = 0x00000080
+	NFS4_ACE_WRITE_ATTRIBUTES  AccessMask = 0x00000100
+	NFS4_ACE_DELETE            AccessMask = 0x00010000
+	NFS4_ACE_READ_ACL          AccessMask = 0x00020000
+	NFS4_ACE_WRITE_ACL         AccessMask = 0x00040000
+	NFS4_ACE_WRITE_OWNER       AccessMask = 0x00080000
+	NFS4_ACE_SYNCHRONIZE       AccessMask = 0x00100000
+)
+
+//Generic permission bundles, as in the kernel's nfs4.h. nfs4_setfacl accepts
+//them as R, W and X; see GenericWriteMask for the directory variant of W
+const (
+	//READ_DATA | READ_ATTRIBUTES | READ_ACL | SYNCHRONIZE
+	NFS4_ACE_GENERIC_READ AccessMask = 0x00120081
+	//WRITE_DATA | APPEND_DATA | WRITE_ATTRIBUTES | WRITE_ACL | SYNCHRONIZE
+	NFS4_ACE_GENERIC_WRITE AccessMask = 0x00160106
+	//EXECUTE | READ_ATTRIBUTES | READ_ACL | SYNCHRONIZE
+	NFS4_ACE_GENERIC_EXECUTE AccessMask = 0x001200A0
+)
+
+//Composite masks mirroring the standard Windows permission sets, for ACLs
+//shared with SMB clients
+const (
+	//Every defined access mask bit
+	NFS4_ACE_MASK_ALL AccessMask = 0x001F01FF
+	//Windows Full control: every bit
+	NFS4_ACE_FULL_CONTROL AccessMask = NFS4_ACE_MASK_ALL
+	//Windows Modify: full control but DELETE_CHILD, WRITE_ACL and WRITE_OWNER
+	NFS4_ACE_MODIFY AccessMask = 0x001301BF
+	//Windows Read & execute: READ_DATA | READ_NAMED_ATTRS | EXECUTE |
+	//READ_ATTRIBUTES | READ_ACL | SYNCHRONIZE
+	NFS4_ACE_READ_EXECUTE AccessMask = 0x001200A9
+)
+
+const (
+	PERM_READ_DATA   = 'r'
+	PERM_WRITE_DATA  = 'w'
+	PERM_APPEND_DATA = 'a'
+
+	PERM_LIST_DIR      = PERM_READ_DATA
+	PERM_CREATE_FILE   = PERM_WRITE_DATA
+	PERM_CREATE_SUBDIR = PERM_APPEND_DATA
+	PERM_DELETE_CHILD  = 'D'
+
+	PERM_DELETE           = 'd'
+	PERM_EXECUTE          = 'x'
+	PERM_READ_ATTR        = 't'
+	PERM_WRITE_ATTR       = 'T'
+	PERM_READ_NAMED_ATTR  = 'n'
+	PERM_WRITE_NAMED_ATTR = 'N'
+	PERM_READ_ACL         = 'c'
+	PERM_WRITE_ACL        = 'C'
+	PERM_WRITE_OWNER      = 'o'
+	PERM_SYNCHRONIZE      = 'y'
+
+	PERM_GENERIC_READ    = 'R'
+	PERM_GENERIC_WRITE   = 'W'
+	PERM_GENERIC_EXECUTE = 'X'
+)
+
+//Concrete mask bits granted by R
+func GenericReadMask(isDir bool) AccessMask {
+	return NFS4_ACE_GENERIC_READ
+}
+
+//Concrete mask bits granted by W. On directories this includes DELETE_CHILD,
+//as nfs4_setfacl does, so that W allows removing entries
+func GenericWriteMask(isDir bool) AccessMask {
+	if isDir {
+		return NFS4_ACE_GENERIC_WRITE | NFS4_ACE_DELETE_CHILD
+	}
+	return NFS4_ACE_GENERIC_WRITE
+}
+
+//Concrete mask bits granted by X
+func GenericExecuteMask(isDir bool) AccessMask {
+	return NFS4_ACE_GENERIC_EXECUTE
+}
+
+//Mask equivalent to the Windows Full control permission. DELETE_CHILD only
+//means something on directories, so files get every bit but that one
+func FullControl(isDir bool) AccessMask {
+	if isDir {
+		return NFS4_ACE_FULL_CONTROL
+	}
+	return NFS4_ACE_FULL_CONTROL &^ NFS4_ACE_DELETE_CHILD
+}
+
+//Mask equivalent to the Windows Modify permission: all of FullControl but
+//deleting children and changing the ACL or owner. The same for files and
+//directories, as Windows has it
+func Modify(isDir bool) AccessMask {
+	return NFS4_ACE_MODIFY
+}
+
+//Mask equivalent to the Windows Read & execute permission, which on
+//directories is listing and traversing them
+func ReadAndExecute(isDir bool) AccessMask {
+	return NFS4_ACE_READ_EXECUTE
+}
+
+//ACL is an ordered list of ACEs. Methods modify the ACL in place and it is
+//not safe for concurrent use; give each goroutine its own Clone. ACEs never
+//alias between an ACL and its callers: ACEs passed in are stored as copies,
+//and ACEs handed out are copies too, so editing them changes nothing until
+//they are put back with ReplaceACE
+type ACL struct {
+	isDirectory bool
+	aceList     []ACE
+}
+
+//Creates an ACL for a directory or a file holding copies of aces, in order
+func NewACL(isDir bool, aces ...*ACE) *ACL {
+	acl := &ACL{
+		isDirectory: isDir,
+		aceList:     make([]ACE, 0, len(aces)),
+	}
+	for _, ace := range aces {
+		acl.AppendACE(ace)
+	}
+
+	return acl
+}
+
+func XAttrLoad(value []byte, isDir bool) (newACL *ACL, err error) {
+	newACL = &ACL{
+		isDirectory: isDir,
+	}
+
+	//This could probably be a constant '4' but i feel safer measuring
+	curAtom := int(0)
+	maxAtom := len(value)
+	if maxAtom < ATOM_SIZE {
+		err = errors.New("invalid input buffer 'value'")
+		return
+	}
+
+	//value is an array of bytes
+	//the ACL data is stored as 32bit ints in this array
+	//we read this data by stepping 1 32bit at a time through the array
+	//ACL Packing structure:
+	// [numAces]{ACE}{ACE}{ACE}
+
+	//We make sure we convert FROM network byte order as a uint32
+	numAces := int(binary.BigEndian.Uint32(value[curAtom:]))
+	if err = GetLimits().check(numAces, maxAtom); err != nil {
+		return
+	}
+	//every ACE takes at least 4 atoms, don't trust a count the buffer can't hold
+	if numAces > (maxAtom-ATOM_SIZE)/(ATOM_SIZE*4) {
+		err = errors.New("buffer overflow")
+		return
+	}
+
+	//the ACEs live in one block and the whos are slices of one string holding
+	//just the who bytes, so a load costs a handful of allocations however
+	//long the ACL is, and keeps nothing of value alive
+	newACL.aceList = make([]ACE, numAces)
+	var whos strings.Builder
+	whos.Grow(maxAtom - ATOM_SIZE - numAces*ATOM_SIZE*4)
+	normalization := GetWhoNormalization()
+	interner := currentWhoInterner()
+
+	//increment our pointer to the next uint32
+	curAtom += ATOM_SIZE
+
+	for curAce := 0; curAce < numAces; curAce++ {
+		//sanity check our boundaries
+		if curAtom >= maxAtom {
+			err = errors.New("buffer overflow")
+			return
+		}
+
+		//ACE Packing structure:
+		// [type][flag][AccessMask][who_Len][who_str]{whoLen}
+
+		//verify there's room in the buffer for the next 4 uint32s
+		if (curAtom + (ATOM_SIZE * 4)) >= maxAtom {
+			err = errors.New("buffer overflow")
+			return
+		}
+
+		//retrieve type
+		aceType := AceType(binary.BigEndian.Uint32(value[curAtom:]))
+		curAtom += ATOM_SIZE //increment ptr
+
+		//retrieve flag
+		aceFlag := AceFlags(binary.BigEndian.Uint32(value[curAtom:]))
+		curAtom += ATOM_SIZE //increment ptr
+
+		//retrieve access mask
+		aceMask := AccessMask(binary.BigEndian.Uint32(value[curAtom:]))
+		curAtom += ATOM_SIZE //increment ptr
+
+		//get the size, in bytes, of the Who string
+		whoLen := int(binary.BigEndian.Uint32(value[curAtom:]))
+		curAtom += ATOM_SIZE //increment ptr
+		if whoLen > maxAtom-curAtom {
+			err = errors.New("buffer overflow")
+			return
+		}
+
+		//retrieve the Who string. Earlier results of String stay valid as the
+		//builder grows
+		whoStart := whos.Len()
+		whos.Write(value[curAtom:(whoLen + curAtom)])
+		aceWho := whos.String()[whoStart:]
+		if !utf8.ValidString(aceWho) {
+			currentLogger().Warnf("nfs4acl: who %q of ace %d is not valid UTF-8", aceWho, curAce+1)
+		}
+		//and increment the pointer
+		curAtom += AceWhoStringAtomLength(whoLen)
+
+		//fill in the ACE and add it to our ACL struct
+		aceWho = normalization.Normalize(aceWho)
+		if interner != nil {
+			aceWho = interner.Intern(aceWho)
+		}
+		newACL.aceList[curAce] = ACE{
+			AceType:    aceType,
+			WhoType:    AceGetWhoType(aceWho),
+			Who:        aceWho,
+			Flags:      aceFlag,
+			AccessMask: aceMask,
+		}
+	}
+
+	if curAtom < maxAtom {
+		currentLogger().Warnf("nfs4acl: ignoring %d trailing bytes after %d aces", maxAtom-curAtom, numAces)
+	}
+
+	return //returns newACL, err
+}
+
+//We reset our slice... this won't garbage collect the old aces, but that's ok because the ACLs are short lived anyways
+func (acl *ACL) ClearACEs() error {
+	acl.aceList = acl.aceList[:0]
+	return nil
+}
+
+func (acl *ACL) AddACE(aceType AceType, aceFlags AceFlags, aceMask AccessMask, aceWho string) {
+	acl.aceList = append(acl.aceList, *NewACE(aceType, aceFlags, aceMask, aceWho))
+}
+
+//Returns a deep copy of the ACL, safe to modify independently
+func (acl *ACL) Clone() *ACL {
+	clone := &ACL{
+		isDirectory: acl.isDirectory,
+		aceList:     make([]ACE, len(acl.aceList)),
+	}
+	copy(clone.aceList, acl.aceList)
+
+	return clone
+}
+
+//Appends a copy of ace to the end of the ACL
+func (acl *ACL) AppendACE(ace *ACE) {
+	acl.aceList = append(acl.aceList, *ace)
+}
+
+//Number of ACEs in the ACL
+func (acl *ACL) Len() int {
+	return len(acl.aceList)
+}
+
+//Reports whether the ACL belongs to a directory, which decides how the
+//permissions are named and what W stands for when printing and parsing
+func (acl *ACL) IsDirectory() bool {
+	return acl.isDirectory
+}
+
+//Retargets the ACL at a directory or a file, e.g. when copying it from one to
+//the other. The ACEs are left as they are, see StripInheritOnly for files
+func (acl *ACL) SetIsDirectory(isDir bool) {
+	acl.isDirectory = isDir
+}
+
+//Returns copies of the ACEs in order
+func (acl *ACL) ACEs() []*ACE {
+	copies := make([]ACE, len(acl.aceList))
+	copy(copies, acl.aceList)
+	aces := make([]*ACE, len(copies))
+	for i := range copies {
+		aces[i] = &copies[i]
+	}
+	return aces
+}
+
+//Returns copies of the ACEs grouped by who, each group in ACL order. Ranging
+//over Whos instead of the map gives a stable order
+func (acl *ACL) ByWho() map[string][]*ACE {
+	groups := make(map[string][]*ACE)
+	for i := range acl.aceList {
+		aceCopy := acl.aceList[i]
+		groups[aceCopy.Who] = append(groups[aceCopy.Who], &aceCopy)
+	}
+	return groups
+}
+
+//Returns the distinct whos of the ACL in order of first appearance
+func (acl *ACL) Whos() []string {
+	var whos []string
+	seen := make(map[string]bool)
+	for _, ace := range acl.aceList {
+		if !seen[ace.Who] {
+			seen[ace.Who] = true
+			whos = append(whos, ace.Who)
+		}
+	}
+	return whos
+}
+
+//Inserts a copy of ace so it ends up at the 0-based index, shifting later
+//ACEs down. An index equal to Len() appends
+func (acl *ACL) InsertACE(index int, ace *ACE) error {
+	if index < 0 || index > len(acl.aceList) {
+		return errors.New("ace index out of range")
+	}
+
+	acl.aceList = append(acl.aceList, ACE{})
+	copy(acl.aceList[index+1:], acl.aceList[index:])
+	acl.aceList[index] = *ace
+	return nil
+}
+
+//Removes the ACE at the 0-based index
+func (acl *ACL) RemoveACE(index int) error {
+	if index < 0 || index >= len(acl.aceList) {
+		return errors.New("ace index out of range")
+	}
+
+	acl.aceList = append(acl.aceList[:index], acl.aceList[index+1:]...)
+	return nil
+}
+
+//Replaces the ACE at the 0-based index with a copy of ace
+func (acl *ACL) ReplaceACE(index int, ace *ACE) error {
+	if index < 0 || index >= len(acl.aceList) {
+		return errors.New("ace index out of range")
+	}
+
+	acl.aceList[index] = *ace
+	return nil
+}
+
+//Moves the ACE at the 0-based index from so it ends up at index to, shifting
+//the ACEs in between. Order decides which ACE wins, so this changes meaning
+func (acl *ACL) MoveACE(from, to int) error {
+	if from < 0 || from >= len(acl.aceList) || to < 0 || to >= len(acl.aceList) {
+		return errors.New("ace index out of range")
+	}
+
+	ace := acl.aceList[from]
+	if from < to {
+		copy(acl.aceList[from:to], acl.aceList[from+1:to+1])
+	} else {
+		copy(acl.aceList[to+1:from+1], acl.aceList[to:from])
+	}
+	acl.aceList[to] = ace
+	return nil
+}
+
+//Exchanges the ACEs at the 0-based indexes i and j
+func (acl *ACL) SwapACEs(i, j int) error {
+	if i < 0 || i >= len(acl.aceList) || j < 0 || j >= len(acl.aceList) {
+		return errors.New("ace index out of range")
+	}
+
+	acl.aceList[i], acl.aceList[j] = acl.aceList[j], acl.aceList[i]
+	return nil
+}
+
+//Returns the index of the first ACE equal to ace, or -1
+func (acl *ACL) IndexOf(ace *ACE) int {
+	for i, cur := range acl.aceList {
+		if cur.Equal(ace) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+//Parses an ACL spec in the context of this ACL, see ParseACLSpec
+func (acl *ACL) ParseACEs(spec string) ([]*ACE, error) {
+	return ParseACLSpec(spec, acl.isDirectory)
+}
+
+func (acl *ACL) PrintACL(verbose bool) error {
+	for _, ace := range acl.aceList {
+		ace.PrintACE(verbose, acl.isDirectory)
+	}
+	return nil
+}
+
+func (acl *ACL) XAttrSize() (xAttrSize int) {
+	//ACL Packing structure:
+	// [num_aces]{ACE}{ACE}{ACE}
+	//ACE Counter, 1 atom to count the # of aces
+	xAttrSize = ATOM_SIZE
+
+	//ACE Packing structure:
+	// [type][flag][AccessMask][who_Len][who_str]{who_len}
+	for _, ace := range acl.aceList {
+		//each ACE has 4 atom's to store type, flag, access mask and wholen
+		xAttrSize += ATOM_SIZE * 4
+		//and add space for the whostring
+		xAttrSize += AceWhoStringAtomLength(len(ace.Who))
+	}
+
+	//and that's all
+	return
+}
+
+//Serializes the ACL into the xattr wire format, after checking every who
+//with ValidateWho and the whole ACL against the Limits. Only the wire flags
+//are written, the pseudo flags and undefined bits are left out
+func (acl *ACL) PackXAttr() (xattr []byte, err error) {
+	if err = acl.checkPack(); err != nil {
+		return nil, err
+	}
+
+	return acl.pack(), nil
+}
+
+//Checks the ACL can be packed, see PackXAttr
+func (acl *ACL) checkPack() error {
+	if err := GetLimits().check(len(acl.aceList), acl.XAttrSize()); err != nil {
+		return err
+	}
+	for i, ace := range acl.aceList {
+		if err := ValidateWho(ace.Who); err != nil {
+			return fmt.Errorf("ace %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+//Serializes the ACL into the xattr wire format
+func (acl *ACL) pack() (xattr []byte) {
+	return acl.packTo(nil)
+}
+
+//Same as pack, but writes into buf when it is large enough
+func (acl *ACL) packTo(buf []byte) (xattr []byte) {
+	xattr = growBuffer(buf, acl.XAttrSize())
+	currAtom := int(0)
+
+	//ACL Packing structure:
+	// [num_aces]{ACE}{ACE}{ACE}
+	// pack number of aces as a uint32 into the buffer
+	// use BigEndian for Network Byte order
+	binary.BigEndian.PutUint32(xattr[currAtom:], uint32(len(acl.aceList)))
+	currAtom += ATOM_SIZE
+
+	//ACE Packing structure:
+	// [type][flag][AccessMask][who_Len][who_str]{who_len}
+	for _, ace := range acl.aceList {
+		//write ace type
+		binary.BigEndian.PutUint32(xattr[currAtom:], uint32(ace.AceType))
+		currAtom += ATOM_SIZE
+		//write ace Flags, the pseudo flags stay behind
+		binary.BigEndian.PutUint32(xattr[currAtom:], uint32(ace.Flags&nfs4AceWireFlags))
+		currAtom += ATOM_SIZE
+		//write ace access mask
+		binary.BigEndian.PutUint32(xattr[currAtom:], uint32(ace.AccessMask))
+		currAtom += ATOM_SIZE
+		//write ace whoLen
+		whoLen := len(ace.Who)
+		binary.BigEndian.PutUint32(xattr[currAtom:], uint32(whoLen))
+		currAtom += ATOM_SIZE
+
+		//Write the Who string into the data
+		copy(xattr[currAtom:], ace.Who)
+		//and zero the padding, buf may hold an earlier value
+		padded := currAtom + AceWhoStringAtomLength(whoLen)
+		for i := currAtom + whoLen; i < padded; i++ {
+			xattr[i] = 0
+		}
+		currAtom = padded
+	}
+
+	return
+}
+
+func (acl *ACL) ApplyAccessMask(accessMask AccessMask) {
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		ace.applyAccessMask(accessMask)
+	}
+}
+
+// Similar to applyAccessMaskByWho, but the whoType matching is faster if usable
+func (acl *ACL) ApplyAccessMaskByWhoType(accessMask AccessMask, whoType uint) error {
+	if whoType == NFS4_ACL_WHO_NAMED { return errors.New("named who not allowed")
+	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_SERVICE {
+		return errors.New("unsupported who type")
+	}
+
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only apply if the whotype matches
+		if ace.WhoType == whoType {
+			ace.applyAccessMask(accessMask)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) ApplyAccessMaskByWho(accessMask AccessMask, who string) error {
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only apply if the who matches
+		if ace.Who == who {
+			ace.applyAccessMask(accessMask)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) RemoveAccessMask(accessMask AccessMask) {
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		ace.removeAccessMask(accessMask)
+	}
+}
+
+// Similar to removeAccessMaskByWho, but the whoType matching is faster if usable
+func (acl *ACL) RemoveAccessMaskByWhoType(accessMask AccessMask, whoType uint) error {
+	if whoType == NFS4_ACL_WHO_NAMED {
+		return errors.New("named who not allowed")
+	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_SERVICE {
+		return errors.New("unsupported who type")
+	}
+
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only remove if the whotype matches
+		if ace.WhoType == whoType {
+			ace.removeAccessMask(accessMask)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) RemoveAccessMaskByWho(accessMask AccessMask, who string) error {
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only remove if the who matches
+		if ace.Who == who {
+			ace.removeAccessMask(accessMask)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) SetAccessMask(accessMask AccessMask) {
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		ace.setAccessMask(accessMask)
+	}
+}
+
+// Similar to setAccessMaskByWho, but the whoType matching is faster if usable
+func (acl *ACL) SetAccessMaskByWhoType(accessMask AccessMask, whoType uint) error {
+	if whoType == NFS4_ACL_WHO_NAMED {
+		return errors.New("named who not allowed")
+	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_SERVICE {
+		return errors.New("unsupported who type")
+	}
+
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only set if the whotype matches
+		if ace.WhoType == whoType {
+			ace.setAccessMask(accessMask)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) SetAccessMaskByWho(accessMask AccessMask, who string) error {
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only set if the who matches
+		if ace.Who == who {
+			ace.setAccessMask(accessMask)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) ApplyFlags(flags AceFlags) {
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		ace.ApplyFlags(flags)
+	}
+}
+
+// Similar to applyFlagsByWho, but the whoType matching is faster if usable
+func (acl *ACL) ApplyFlagsByWhoType(flags AceFlags, whoType uint) error {
+	if whoType == NFS4_ACL_WHO_NAMED {
+		return errors.New("named who not allowed")
+	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_SERVICE {
+		return errors.New("unsupported who type")
+	}
+
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only apply if the whotype matches
+		if ace.WhoType == whoType {
+			ace.ApplyFlags(flags)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) ApplyFlagsByWho(flags AceFlags, who string) error {
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only apply if the who matches
+		if ace.Who == who {
+			ace.ApplyFlags(flags)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) RemoveFlags(flags AceFlags) {
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		ace.RemoveFlags(flags)
+	}
+}
+
+// Similar to removeFlagsByWho, but the whoType matching is faster if usable
+func (acl *ACL) RemoveFlagsByWhoType(flags AceFlags, whoType uint) error {
+	if whoType == NFS4_ACL_WHO_NAMED {
+		return errors.New("named who not allowed")
+	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_SERVICE {
+		return errors.New("unsupported who type")
+	}
+
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only remove if the whotype matches
+		if ace.WhoType == whoType {
+			ace.RemoveFlags(flags)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) RemoveFlagsByWho(flags AceFlags, who string) error {
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only remove if the who matches
+		if ace.Who == who {
+			ace.RemoveFlags(flags)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) SetFlags(flags AceFlags) {
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		ace.SetFlags(flags)
+	}
+}
+
+// Similar to setFlagsByWho, but the whoType matching is faster if usable
+func (acl *ACL) SetFlagsByWhoType(flags AceFlags, whoType uint) error {
+	if whoType == NFS4_ACL_WHO_NAMED {
+		return errors.New("named who not allowed")
+	} else if whoType < NFS4_ACL_WHO_NAMED || whoType > NFS4_ACL_WHO_SERVICE {
+		return errors.New("unsupported who type")
+	}
+
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only set if the whotype matches
+		if ace.WhoType == whoType {
+			ace.SetFlags(flags)
+		}
+	}
+
+	return nil
+}
+
+func (acl *ACL) SetFlagsByWho(flags AceFlags, who string) error {
+	//iterate our ace's
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		//and only set if the who matches
+		if ace.Who == who {
+			ace.SetFlags(flags)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl_test
+
+import (
+	"encoding/binary"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/cclose/libnfs4acl-go/v2/nfs4acltest"
+	"math/rand"
+	"testing"
+)
+
+func TestPackXAttrRoundTrip(t *testing.T) {
+	for _, spec := range []string{
+		"A::OWNER@:rwatTnNcCoy",
+		"A::OWNER@:rwatTnNcCoy,A:g:GROUP@:rtncy,A::EVERYONE@:rtncy",
+		"D::bob@example.com:w,A:fdg:staff@example.com:rwaDdxtTnNcCoy,A::1000:r",
+		"A::a:r,A::ab:r,A::abc:r,A::abcd:r,A::abcde:r",
+	} {
+		for _, isDir := range []bool{false, true} {
+			want := nfs4acltest.ACL(t, spec, isDir)
+			xattr, err := want.PackXAttr()
+			if err != nil {
+				t.Fatalf("%s: %v", spec, err)
+			}
+			if len(xattr) != want.XAttrSize() {
+				t.Errorf("%s: packed %d bytes, XAttrSize says %d", spec, len(xattr), want.XAttrSize())
+			}
+
+			got, err := nfs4acl.XAttrLoad(xattr, isDir)
+			if err != nil {
+				t.Fatalf("%s: %v", spec, err)
+			}
+			nfs4acltest.AssertEqual(t, got, want)
+		}
+	}
+}
+
+func TestXAttrLoadWireFormat(t *testing.T) {
+	//one ACE: ALLOWED, no flags, READ_DATA, "OWNER@" padded to 8 bytes
+	xattr := []byte{
+		0, 0, 0, 1,
+		0, 0, 0, 0,
+		0, 0, 0, 0,
+		0, 0, 0, 1,
+		0, 0, 0, 6,
+		'O', 'W', 'N', 'E', 'R', '@', 0, 0,
+	}
+	acl, err := nfs4acl.XAttrLoad(xattr, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nfs4acltest.AssertEqual(t, acl, nfs4acltest.ACL(t, "A::OWNER@:r", false))
+
+	packed, err := acl.PackXAttr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(packed) != string(xattr) {
+		t.Errorf("packed as %v, want %v", packed, xattr)
+	}
+}
+
+func TestXAttrLoadRejectsTruncated(t *testing.T) {
+	//the last who needs no padding, so every byte counts
+	xattr := nfs4acltest.XAttr(t, "A::OWNER@:rw,D::abcd:r", false)
+	for size := 0; size < len(xattr); size++ {
+		if _, err := nfs4acl.XAttrLoad(xattr[:size], false); err == nil {
+			t.Errorf("%d of %d bytes accepted", size, len(xattr))
+		}
+	}
+
+	//a count the buffer can't possibly hold
+	huge := append([]byte(nil), xattr...)
+	binary.BigEndian.PutUint32(huge, 1000)
+	if _, err := nfs4acl.XAttrLoad(huge, false); err == nil {
+		t.Error("ace count beyond the buffer accepted")
+	}
+}
+
+func TestACLCopiesACEs(t *testing.T) {
+	ace := nfs4acl.NewACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, nfs4acl.NFS4_ACE_READ_DATA, nfs4acl.NFS4_ACL_WHO_OWNER_STRING)
+	acl := nfs4acl.NewACL(false, ace)
+	ace.AccessMask = nfs4acl.NFS4_ACE_WRITE_DATA
+	acl.ACEs()[0].AccessMask = nfs4acl.NFS4_ACE_WRITE_DATA
+	nfs4acltest.AssertEqual(t, acl, nfs4acltest.ACL(t, "A::OWNER@:r", false))
+
+	clone := acl.Clone()
+	clone.ApplyAccessMask(nfs4acl.NFS4_ACE_WRITE_DATA)
+	nfs4acltest.AssertEqual(t, acl, nfs4acltest.ACL(t, "A::OWNER@:r", false))
+	nfs4acltest.AssertEqual(t, clone, nfs4acltest.ACL(t, "A::OWNER@:rw", false))
+}
+
+func TestACLEditing(t *testing.T) {
+	acl := nfs4acltest.ACL(t, "A::OWNER@:r,A:g:GROUP@:r,A::EVERYONE@:r", false)
+	if err := acl.MoveACE(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	nfs4acltest.AssertEqual(t, acl, nfs4acltest.ACL(t, "A::EVERYONE@:r,A::OWNER@:r,A:g:GROUP@:r", false))
+
+	if err := acl.RemoveACE(1); err != nil {
+		t.Fatal(err)
+	}
+	ace, _ := nfs4acl.ParseACE("D::bob@example.com:w", false)
+	if err := acl.InsertACE(0, ace); err != nil {
+		t.Fatal(err)
+	}
+	nfs4acltest.AssertEqual(t, acl, nfs4acltest.ACL(t, "D::bob@example.com:w,A::EVERYONE@:r,A:g:GROUP@:r", false))
+	if i := acl.IndexOf(ace); i != 0 {
+		t.Errorf("IndexOf %d, want 0", i)
+	}
+
+	if err := acl.SetAccessMaskByWhoType(nfs4acl.NFS4_ACE_READ_DATA|nfs4acl.NFS4_ACE_EXECUTE, nfs4acl.NFS4_ACL_WHO_GROUP); err != nil {
+		t.Fatal(err)
+	}
+	nfs4acltest.AssertEqual(t, acl, nfs4acltest.ACL(t, "D::bob@example.com:w,A::EVERYONE@:r,A:g:GROUP@:rx", false))
+
+	if err := acl.RemoveACE(3); err == nil {
+		t.Error("out of range index accepted")
+	}
+}
+
+func TestPackXAttrLeavesOutPseudoFlags(t *testing.T) {
+	flags, err := nfs4acl.ParseFlags("fO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl := nfs4acl.NewACL(true,
+		nfs4acl.NewACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, flags, nfs4acl.NFS4_ACE_READ_DATA, "OWNER@"),
+		nfs4acl.NewACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, nfs4acl.NFS4_ACE_EVERYONE|nfs4acl.NFS4_ACE_INHERITED_ACE,
+			nfs4acl.NFS4_ACE_READ_DATA, "EVERYONE@"))
+
+	xattr, err := acl.PackXAttr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//count, then type and flags of the first ace
+	if flags := binary.BigEndian.Uint32(xattr[8:]); flags != uint32(nfs4acl.NFS4_ACE_FILE_INHERIT_ACE) {
+		t.Errorf("first ace packed with flags %#x", flags)
+	}
+	loaded, err := nfs4acl.XAttrLoad(xattr, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags := loaded.ACEs()[1].Flags; flags != nfs4acl.NFS4_ACE_INHERITED_ACE {
+		t.Errorf("second ace loaded with flags %#x", uint32(flags))
+	}
+}
+
+//A directory ACL of 16 ACEs or a few more, the same every run
+func benchmarkACL() *nfs4acl.ACL {
+	r := rand.New(rand.NewSource(1))
+	acl := nfs4acl.NewACL(true)
+	for acl.Len() < 16 {
+		for _, ace := range nfs4acltest.RandomACL(r, true).ACEs() {
+			acl.AppendACE(ace)
+		}
+	}
+	return acl
+}
+
+func BenchmarkXAttrLoad(b *testing.B) {
+	xattr, err := benchmarkACL().PackXAttr()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(xattr)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := nfs4acl.XAttrLoad(xattr, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPackXAttr(b *testing.B) {
+	acl := benchmarkACL()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(acl.XAttrSize()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := acl.PackXAttr(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
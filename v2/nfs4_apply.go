@@ -0,0 +1,169 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync"
+)
+
+//MutateFunc modifies acl in place and reports whether anything changed.
+//It may be called concurrently from several workers, each with its own ACL
+type MutateFunc func(acl *ACL) (changed bool, err error)
+
+//A path handed from the walker to the worker pool
+type applyJob struct {
+	path  string
+	isDir bool
+	info  fs.FileInfo //as found by the walk
+	acl   *ACL        //precomputed ACL to write, if the operation needs one
+}
+
+//Walks the tree rooted at root, fetching every ACL, passing it to mutate and
+//writing it back only when mutate reports a change. Paths are processed by a
+//pool of workers (see WithWorkers), so the order of mutations is not defined.
+//A path replaced while being changed fails with a *StaleSkipError, which an
+//error handler may choose to let pass (see IsStaleSkip)
+func ApplyRecursive(root string, mutate MutateFunc, opts ...Option) error {
+	return ApplyRecursiveContext(context.Background(), root, mutate, opts...)
+}
+
+//Same as ApplyRecursive, but stops handing out paths as soon as ctx is done
+//and returns ctx.Err(). Changes already written are left in place
+func ApplyRecursiveContext(ctx context.Context, root string, mutate MutateFunc, opts ...Option) error {
+	return runBulk(ctx, root, newOptions(opts), nil, func(job applyJob) (*ACL, int, error) {
+		return applyMutation(ctx, job, mutate, opts...)
+	})
+}
+
+//Shared engine of the recursive operations. The tree is walked on the calling
+//goroutine, where prepare (if set) sees every path in tree order and may fill
+//in the job or skip it. Jobs are then handed to a pool of workers running
+//process, which returns the resulting ACL and the size of the xattr written,
+//or 0 if none was
+func runBulk(ctx context.Context, root string, o *options,
+	prepare func(job *applyJob) (skip bool, err error),
+	process func(job applyJob) (*ACL, int, error)) error {
+
+	progress := newProgressTracker(o)
+	defer progress.finish()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	stop := make(chan struct{})
+	fail := func(path string, err error) {
+		progress.errored.Add(1)
+		if o.onError != nil {
+			handled := err
+			if err = o.onError(path, err); err == nil {
+				o.log().Warnf("nfs4acl: %s: %v (handled, continuing)", path, handled)
+				return
+			}
+		}
+		once.Do(func() {
+			firstErr = err
+			close(stop)
+		})
+	}
+
+	jobs := make(chan applyJob)
+	for i := 0; i < o.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				//drain the queue without touching anything once cancelled
+				if ctx.Err() != nil {
+					continue
+				}
+				progress.scanned.Add(1)
+				acl, written, err := process(job)
+				if err != nil {
+					fail(job.path, err)
+					continue
+				}
+				if written > 0 {
+					progress.changed.Add(1)
+					progress.bytesWritten.Add(int64(written))
+				}
+				if o.onResult != nil {
+					o.onResult(job.path, acl.Clone(), written > 0)
+				}
+			}
+		}()
+	}
+
+	walkErr := walkPaths(ctx, root, o.walk, o.log(), func(path string, info fs.FileInfo, isDir bool, err error) error {
+		job := applyJob{path: path, isDir: isDir, info: info}
+		skip := false
+		if err == nil && prepare != nil {
+			skip, err = prepare(&job)
+		}
+
+		if err != nil {
+			fail(path, err)
+		} else if !skip {
+			select {
+			case jobs <- job:
+			case <-stop:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		select {
+		case <-stop:
+			return filepath.SkipAll
+		default:
+			return nil
+		}
+	})
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if walkErr == nil {
+		//workers may have skipped queued paths after the walk finished
+		walkErr = ctx.Err()
+	}
+	return walkErr
+}
+
+//Single get/mutate/set cycle for one path, returning the resulting ACL and
+//the size of the xattr written or 0 if the ACL was left alone. A write that
+//goes stale is only retried on the file the walk found
+func applyMutation(ctx context.Context, job applyJob, mutate MutateFunc, opts ...Option) (*ACL, int, error) {
+	path := job.path
+	acl, err := LoadACL(path, job.isDir, opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	changed, err := mutate(acl)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", path, err)
+	}
+	if !changed {
+		return acl, 0, nil
+	}
+
+	//a followed link says nothing about its target
+	var want fs.FileInfo
+	if job.info != nil && job.info.Mode()&fs.ModeSymlink == 0 {
+		want = job.info
+	}
+	if err = SetACLContext(ctx, path, acl, append([]Option{withExpectedFile(want)}, opts...)...); err != nil {
+		return nil, 0, err
+	}
+	return acl, acl.XAttrSize(), nil
+}
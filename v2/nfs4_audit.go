@@ -0,0 +1,78 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//AuditRecord describes one attempted ACL write
+type AuditRecord struct {
+	Time     time.Time
+	Path     string
+	Old      *ACL //nil if the previous ACL couldn't be read
+	New      *ACL
+	Metadata map[string]string //as attached with WithAuditMetadata
+	Err      error             //outcome of the write
+}
+
+//AuditHook receives a record for every ACL write made by the library. It is
+//called synchronously after the write, possibly from several goroutines
+type AuditHook func(AuditRecord)
+
+var (
+	auditMu   sync.RWMutex
+	auditHook AuditHook
+)
+
+type auditMetadataKey struct{}
+
+//Installs hook for all subsequent ACL writes, nil removes it. While a hook is
+//installed every write first reads the current ACL to report it as Old
+func SetAuditHook(hook AuditHook) {
+	auditMu.Lock()
+	auditHook = hook
+	auditMu.Unlock()
+}
+
+//Attaches caller metadata (ticket, operator, reason...) to ctx. Writes made
+//with ctx, e.g. by SetACLContext or ApplyRecursiveContext, carry it into their
+//AuditRecord
+func WithAuditMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	return context.WithValue(ctx, auditMetadataKey{}, metadata)
+}
+
+func currentAuditHook() AuditHook {
+	auditMu.RLock()
+	defer auditMu.RUnlock()
+	return auditHook
+}
+
+//Wraps a write with the audit hook, if one is installed
+func auditedWrite(ctx context.Context, path string, acl *ACL, write func() error) error {
+	hook := currentAuditHook()
+	if hook == nil {
+		return write()
+	}
+
+	old, oldErr := LoadACL(path, acl.isDirectory)
+	if oldErr != nil {
+		old = nil
+	}
+	err := write()
+
+	metadata, _ := ctx.Value(auditMetadataKey{}).(map[string]string)
+	hook(AuditRecord{
+		Time:     time.Now(),
+		Path:     path,
+		Old:      old,
+		New:      acl.Clone(),
+		Metadata: metadata,
+		Err:      err,
+	})
+	return err
+}
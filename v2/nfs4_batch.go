@@ -0,0 +1,66 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"sync"
+)
+
+//Fetches the ACLs of many paths with at most concurrency requests in flight.
+//Every path ends up in exactly one of the returned maps. Duplicate paths are
+//only fetched once, with a single stat each. opts apply to every fetch
+func GetACLs(paths []string, concurrency int, opts ...Option) (map[string]*ACL, map[string]error) {
+	return GetACLsContext(context.Background(), paths, concurrency, opts...)
+}
+
+//Same as GetACLs, but stops fetching once ctx is done. Paths that were not
+//fetched by then are reported with ctx.Err()
+func GetACLsContext(ctx context.Context, paths []string, concurrency int, opts ...Option) (map[string]*ACL, map[string]error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	acls := make(map[string]*ACL, len(paths))
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	jobs := make(chan string)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				var acl *ACL
+				err := ctx.Err()
+				if err == nil {
+					acl, err = GetACL(path, opts...)
+				}
+
+				mu.Lock()
+				if err != nil {
+					errs[path] = err
+				} else {
+					acls[path] = acl
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	return acls, errs
+}
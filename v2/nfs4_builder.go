@@ -0,0 +1,87 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+//ACLBuilder assembles an ACL one entry at a time, e.g.
+//
+//	acl := nfs4acl.Build(true).
+//		Allow("OWNER@", nfs4acl.NFS4_ACE_READ_DATA|nfs4acl.NFS4_ACE_WRITE_DATA).
+//		WithInheritance(nfs4acl.NFS4_ACE_FILE_INHERIT_ACE).
+//		Deny("bob@example.com", nfs4acl.NFS4_ACE_WRITE_ACL).
+//		ACL()
+type ACLBuilder struct {
+	acl          *ACL
+	defaultFlags AceFlags
+}
+
+//Starts an empty ACL for a directory or a file
+func Build(isDir bool) *ACLBuilder {
+	return &ACLBuilder{
+		acl: NewACL(isDir),
+	}
+}
+
+//Sets flags added to every ACE appended from now on, e.g. inheritance flags
+//for a whole directory ACL
+func (b *ACLBuilder) DefaultFlags(flags AceFlags) *ACLBuilder {
+	b.defaultFlags = flags
+	return b
+}
+
+//Appends an ALLOW entry for who
+func (b *ACLBuilder) Allow(who string, mask AccessMask) *ACLBuilder {
+	return b.add(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, mask, who)
+}
+
+//Appends a DENY entry for who
+func (b *ACLBuilder) Deny(who string, mask AccessMask) *ACLBuilder {
+	return b.add(NFS4_ACE_ACCESS_DENIED_ACE_TYPE, 0, mask, who)
+}
+
+//Appends an ALLOW entry for the group who
+func (b *ACLBuilder) AllowGroup(who string, mask AccessMask) *ACLBuilder {
+	return b.add(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, NFS4_ACE_IDENTIFIER_GROUP, mask, who)
+}
+
+//Appends a DENY entry for the group who
+func (b *ACLBuilder) DenyGroup(who string, mask AccessMask) *ACLBuilder {
+	return b.add(NFS4_ACE_ACCESS_DENIED_ACE_TYPE, NFS4_ACE_IDENTIFIER_GROUP, mask, who)
+}
+
+//Appends an AUDIT entry for who. Add NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG
+//and/or NFS4_ACE_FAILED_ACCESS_ACE_FLAG with WithFlags
+func (b *ACLBuilder) Audit(who string, mask AccessMask) *ACLBuilder {
+	return b.add(NFS4_ACE_SYSTEM_AUDIT_ACE_TYPE, 0, mask, who)
+}
+
+//Appends an ALARM entry for who, see Audit
+func (b *ACLBuilder) Alarm(who string, mask AccessMask) *ACLBuilder {
+	return b.add(NFS4_ACE_SYSTEM_ALARM_ACE_TYPE, 0, mask, who)
+}
+
+//Adds flags to the most recently appended ACE. Does nothing on an empty ACL
+func (b *ACLBuilder) WithFlags(flags AceFlags) *ACLBuilder {
+	if n := len(b.acl.aceList); n > 0 {
+		b.acl.aceList[n-1].ApplyFlags(flags)
+	}
+	return b
+}
+
+//Adds inheritance flags to the most recently appended ACE. Bits other than
+//the inheritance flags are ignored
+func (b *ACLBuilder) WithInheritance(flags AceFlags) *ACLBuilder {
+	return b.WithFlags(flags & NFS4_ACE_INHERITANCE_FLAGS)
+}
+
+//Returns the ACL built so far. The builder can keep going afterwards without
+//affecting the returned ACL
+func (b *ACLBuilder) ACL() *ACL {
+	return b.acl.Clone()
+}
+
+func (b *ACLBuilder) add(aceType AceType, flags AceFlags, mask AccessMask, who string) *ACLBuilder {
+	b.acl.aceList = append(b.acl.aceList, *NewACE(aceType, flags|b.defaultFlags, mask, who))
+	return b
+}
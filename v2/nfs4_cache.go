@@ -0,0 +1,116 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"sync"
+	"time"
+)
+
+//Cache memoizes ACLs per path for a fixed time, for long running processes
+//that repeatedly evaluate slowly changing ACLs. Writes made through the
+//cache invalidate the path, other writers are only noticed once the entry
+//expires. Expired entries are swept out as new ones are added, so the cache
+//only holds about what was read within the last ttl. A Cache is safe for
+//concurrent use
+type Cache struct {
+	ttl     time.Duration
+	get     func(string) (*ACL, error)
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	//bumped by every invalidation, reads that started before one aren't stored
+	gen uint64
+	//entry count at which the next sweep runs
+	sweepAt int
+}
+
+type cacheEntry struct {
+	acl     *ACL
+	expires time.Time
+}
+
+//Cache size below which expired entries are left alone
+const cacheMinSweep = 64
+
+//Creates a cache keeping ACLs for ttl
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		get:     func(path string) (*ACL, error) { return GetACL(path) },
+		entries: make(map[string]cacheEntry),
+		sweepAt: cacheMinSweep,
+	}
+}
+
+//Returns the ACL of path, from the cache if it hasn't expired. Errors are not
+//cached. The caller gets its own copy and may modify it
+func (c *Cache) GetACL(path string) (*ACL, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	gen := c.gen
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.acl.Clone(), nil
+	}
+
+	acl, err := c.get(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	//an invalidation while we were reading may mean acl is already stale
+	if gen == c.gen {
+		now := time.Now()
+		c.entries[path] = cacheEntry{
+			acl:     acl.Clone(),
+			expires: now.Add(c.ttl),
+		}
+		if len(c.entries) >= c.sweepAt {
+			c.sweep(now)
+		}
+	}
+	c.mu.Unlock()
+
+	return acl, nil
+}
+
+//Drops the expired entries and sets the next sweep to twice the live count,
+//which keeps inserts amortized O(1). Called with mu held
+func (c *Cache) sweep(now time.Time) {
+	for path, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, path)
+		}
+	}
+	c.sweepAt = 2 * len(c.entries)
+	if c.sweepAt < cacheMinSweep {
+		c.sweepAt = cacheMinSweep
+	}
+}
+
+//Writes the ACL of path and drops the cached entry, so the next read sees
+//what the server actually stored
+func (c *Cache) SetACL(path string, acl *ACL) error {
+	defer c.Invalidate(path)
+	return SetACL(path, acl)
+}
+
+//Drops the cached entry for path
+func (c *Cache) Invalidate(path string) {
+	c.mu.Lock()
+	delete(c.entries, path)
+	c.gen++
+	c.mu.Unlock()
+}
+
+//Drops every cached entry
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	c.entries = make(map[string]cacheEntry)
+	c.sweepAt = cacheMinSweep
+	c.gen++
+	c.mu.Unlock()
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCacheDropsStaleFill(t *testing.T) {
+	c := NewCache(time.Hour)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c.get = func(path string) (*ACL, error) {
+		close(started)
+		<-release
+		return NewACL(false), nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := c.GetACL("/srv"); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-started
+	c.Invalidate("/srv")
+	close(release)
+	<-done
+
+	if _, ok := c.entries["/srv"]; ok {
+		t.Error("read started before Invalidate was cached")
+	}
+
+	c.get = func(path string) (*ACL, error) { return NewACL(false), nil }
+	if _, err := c.GetACL("/srv"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.entries["/srv"]; !ok {
+		t.Error("read after Invalidate wasn't cached")
+	}
+}
+
+func TestCacheSweepsExpired(t *testing.T) {
+	c := NewCache(time.Millisecond)
+	reads := 0
+	c.get = func(path string) (*ACL, error) {
+		reads++
+		return NewACL(false), nil
+	}
+
+	for i := 0; i < 10*cacheMinSweep; i++ {
+		if _, err := c.GetACL(fmt.Sprint("/srv/", i)); err != nil {
+			t.Fatal(err)
+		}
+		if i%cacheMinSweep == 0 {
+			time.Sleep(2 * time.Millisecond)
+		}
+	}
+	if len(c.entries) > 2*cacheMinSweep {
+		t.Errorf("%d entries left after %d reads", len(c.entries), reads)
+	}
+}
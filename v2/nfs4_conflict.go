@@ -0,0 +1,79 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+//Returned, wrapped with the path, by SetACLIf when the ACL changed after the
+//caller read it
+var ErrConflict = errors.New("acl changed since it was read")
+
+//Replaces the ACL of path with acl only if the current ACL still has
+//expectedHash, the ACL.Hash of the ACL the caller based acl on. Otherwise
+//nothing is written and the error wraps ErrConflict; read the ACL again,
+//redo the change and retry. The check and the write are separate calls, so
+//this catches every edit made since the caller's read but not one landing in
+//between the two
+func SetACLIf(path string, expectedHash string, acl *ACL, opts ...Option) error {
+	return SetACLIfContext(context.Background(), path, expectedHash, acl, opts...)
+}
+
+//Same as SetACLIf, ctx carries audit metadata (see WithAuditMetadata)
+func SetACLIfContext(ctx context.Context, path string, expectedHash string, acl *ACL, opts ...Option) error {
+	current, err := GetACL(path, opts...)
+	if err != nil {
+		return err
+	}
+	if current.Hash() != expectedHash {
+		return fmt.Errorf("%s: %w", path, ErrConflict)
+	}
+
+	return SetACLContext(ctx, path, acl, opts...)
+}
+
+//Fetches the ACL of path, lets fn change it in place and writes it back,
+//replacing the get, modify and set every caller would otherwise write. Nothing
+//is written if fn fails, whose error is then returned as is, or leaves the ACL
+//as it was. The write goes through SetACLIf, so an edit made meanwhile isn't lost:
+//the change fails with ErrConflict, or, with WithConflictRetries, starts over
+//on a fresh read. fn may thus run more than once and should derive its change
+//from the ACL it's given
+func ModifyACL(path string, fn func(*ACL) error, opts ...Option) error {
+	return ModifyACLContext(context.Background(), path, fn, opts...)
+}
+
+//Same as ModifyACL, but gives up once ctx is done, returning ctx.Err(). ctx
+//also carries audit metadata (see WithAuditMetadata)
+func ModifyACLContext(ctx context.Context, path string, fn func(*ACL) error, opts ...Option) error {
+	o := newOptions(opts)
+
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		acl, err := GetACL(path, opts...)
+		if err != nil {
+			return err
+		}
+		hash := acl.Hash()
+		if err = fn(acl); err != nil {
+			return err
+		}
+		if acl.Hash() == hash {
+			return nil
+		}
+
+		err = SetACLIfContext(ctx, path, hash, acl, opts...)
+		if !errors.Is(err, ErrConflict) || attempt >= o.conflictRetries {
+			return err
+		}
+		o.log().Debugf("nfs4acl: %s: acl changed while modifying it, retrying", path)
+	}
+}
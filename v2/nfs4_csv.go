@@ -0,0 +1,74 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Header row written by CSVWriter. index counts the ACEs of a path from 1
+var CSVHeader = []string{"path", "index", "type", "flags", "who", "perms", "perm_names"}
+
+//CSVWriter writes ACLs as CSV, one row per ACE of every path, for loading
+//into a spreadsheet. Types are written as words, flags and permissions as
+//letters, with the permission names alongside. Write may be called
+//concurrently
+type CSVWriter struct {
+	out           *csv.Writer
+	headerWritten bool
+	mu            sync.Mutex
+}
+
+//Creates a writer to w. The header row comes with the first Write
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{out: csv.NewWriter(w)}
+}
+
+//Writes a row for every ACE of acl, read from path. An ACL without ACEs
+//writes nothing
+func (w *CSVWriter) Write(path string, acl *ACL) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.headerWritten {
+		w.headerWritten = true
+		if err := w.out.Write(CSVHeader); err != nil {
+			return err
+		}
+	}
+
+	for i, ace := range acl.aceList {
+		err := w.out.Write([]string{
+			path,
+			strconv.Itoa(i + 1),
+			AceTypeString(ace.AceType, true),
+			FlagsString(ace.Flags),
+			ace.Who,
+			PermsString(ace.AccessMask, acl.isDirectory),
+			strings.Join(PermNames(ace.AccessMask, acl.isDirectory), " "),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Flushes the buffered rows, reporting any error of an earlier Write
+func (w *CSVWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.headerWritten {
+		w.headerWritten = true
+		if err := w.out.Write(CSVHeader); err != nil {
+			return err
+		}
+	}
+	w.out.Flush()
+	return w.out.Error()
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/cclose/libnfs4acl-go/v2/nfs4acltest"
+	"reflect"
+	"testing"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := nfs4acl.NewCSVWriter(&buf)
+	if err := w.Write("/a", nfs4acltest.ACL(t, "A::OWNER@:rw,D::EVERYONE@:w", false)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("/b", nfs4acltest.ACL(t, "A:g:GROUP@:r", false)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("%d rows, want 4: %q", len(rows), rows)
+	}
+	if !reflect.DeepEqual(rows[0], nfs4acl.CSVHeader) {
+		t.Errorf("header %q", rows[0])
+	}
+	for i, want := range [][2]string{{"/a", "1"}, {"/a", "2"}, {"/b", "1"}} {
+		row := rows[i+1]
+		if row[0] != want[0] || row[1] != want[1] {
+			t.Errorf("row %d is %s #%s, want %s #%s", i+1, row[0], row[1], want[0], want[1])
+		}
+	}
+	if rows[2][4] != "EVERYONE@" {
+		t.Errorf("who %q", rows[2][4])
+	}
+}
+
+func TestCSVWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := nfs4acl.NewCSVWriter(&buf).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || !reflect.DeepEqual(rows[0], nfs4acl.CSVHeader) {
+		t.Errorf("got %q, want only the header", rows)
+	}
+}
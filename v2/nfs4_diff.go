@@ -0,0 +1,173 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"bytes"
+)
+
+//DiffOp describes what happened to an ACE between two ACLs
+type DiffOp int
+
+const (
+	DIFF_EQUAL DiffOp = iota
+	DIFF_ADDED
+	DIFF_REMOVED
+	DIFF_CHANGED //same type and who, different flags or mask
+)
+
+//DiffEntry is a single ACE of either ACL and what happened to it. For
+//DIFF_CHANGED, Old holds the ACE that was replaced by ACE
+type DiffEntry struct {
+	Op  DiffOp
+	ACE *ACE
+	Old *ACE
+}
+
+//Diff is the ordered edit script turning one ACL into another. ACE order is
+//significant, so a moved ACE shows up as removed and added
+type Diff struct {
+	Entries     []DiffEntry
+	isDirectory bool
+}
+
+//Fetches the current ACL of path and returns how setting acl would change it,
+//without writing anything. Backs the dry runs of nfs4_setfacl --test
+func PreviewSet(path string, acl *ACL) (*Diff, error) {
+	current, err := GetACL(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffACLs(current, acl), nil
+}
+
+//Compares two ACLs ACE by ACE, keeping the longest run of common ACEs
+func DiffACLs(from, to *ACL) *Diff {
+	//copies, so the diff stays valid when either ACL is edited later
+	a, b := from.ACEs(), to.ACEs()
+
+	//classic longest common subsequence table, ACLs are short
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].Equal(b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := &Diff{
+		isDirectory: to.isDirectory,
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i].Equal(b[j]):
+			diff.Entries = append(diff.Entries, DiffEntry{Op: DIFF_EQUAL, ACE: b[j]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff.Entries = append(diff.Entries, DiffEntry{Op: DIFF_REMOVED, ACE: a[i]})
+			i++
+		default:
+			diff.Entries = append(diff.Entries, DiffEntry{Op: DIFF_ADDED, ACE: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff.Entries = append(diff.Entries, DiffEntry{Op: DIFF_REMOVED, ACE: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff.Entries = append(diff.Entries, DiffEntry{Op: DIFF_ADDED, ACE: b[j]})
+	}
+
+	diff.pairChanges()
+	return diff
+}
+
+//Within each run of edits between unchanged ACEs, folds a removed ACE and an
+//added ACE for the same type and who into a single DIFF_CHANGED entry
+func (d *Diff) pairChanges() {
+	var entries []DiffEntry
+	for start := 0; start < len(d.Entries); {
+		if d.Entries[start].Op == DIFF_EQUAL {
+			entries = append(entries, d.Entries[start])
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(d.Entries) && d.Entries[end].Op != DIFF_EQUAL {
+			end++
+		}
+		run := d.Entries[start:end]
+		paired := make([]bool, len(run))
+		for i := range run {
+			if run[i].Op != DIFF_REMOVED {
+				continue
+			}
+			for k := range run {
+				if run[k].Op != DIFF_ADDED || paired[k] ||
+					run[k].ACE.AceType != run[i].ACE.AceType || run[k].ACE.Who != run[i].ACE.Who {
+					continue
+				}
+				paired[k] = true
+				run[i] = DiffEntry{Op: DIFF_CHANGED, ACE: run[k].ACE, Old: run[i].ACE}
+				break
+			}
+		}
+		for i := range run {
+			if !paired[i] {
+				entries = append(entries, run[i])
+			}
+		}
+		start = end
+	}
+
+	d.Entries = entries
+}
+
+//Reports whether the ACLs differ at all
+func (d *Diff) Changed() bool {
+	for _, entry := range d.Entries {
+		if entry.Op != DIFF_EQUAL {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Renders the differences one per line, prefixed with '-' for removed, '+'
+//for added and '~' for changed ACEs, which show the old and the new form
+func (d *Diff) String() string {
+	var buffer bytes.Buffer
+	for _, entry := range d.Entries {
+		switch entry.Op {
+		case DIFF_ADDED:
+			buffer.WriteString("+ ")
+		case DIFF_REMOVED:
+			buffer.WriteString("- ")
+		case DIFF_CHANGED:
+			buffer.WriteString("~ ")
+			buffer.WriteString(entry.Old.Format(false, d.isDirectory))
+			buffer.WriteString(" -> ")
+		default:
+			continue
+		}
+		buffer.WriteString(entry.ACE.Format(false, d.isDirectory))
+		buffer.WriteRune('\n')
+	}
+
+	return buffer.String()
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl_test
+
+import (
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/cclose/libnfs4acl-go/v2/nfs4acltest"
+	"testing"
+)
+
+func TestDiffACLs(t *testing.T) {
+	from := nfs4acltest.ACL(t, "A::OWNER@:rw,A:g:GROUP@:r,A::EVERYONE@:r", false)
+	to := nfs4acltest.ACL(t, "A::OWNER@:rw,A:g:GROUP@:rw,A::bob@example.com:r", false)
+
+	diff := nfs4acl.DiffACLs(from, to)
+	if !diff.Changed() {
+		t.Fatal("differing acls reported unchanged")
+	}
+	want := "~ A:g:GROUP@:r -> A:g:GROUP@:rw\n- A::EVERYONE@:r\n+ A::bob@example.com:r\n"
+	if got := diff.String(); got != want {
+		t.Errorf("diff\n%s\nwant\n%s", got, want)
+	}
+
+	var ops []nfs4acl.DiffOp
+	for _, entry := range diff.Entries {
+		ops = append(ops, entry.Op)
+	}
+	wantOps := []nfs4acl.DiffOp{nfs4acl.DIFF_EQUAL, nfs4acl.DIFF_CHANGED, nfs4acl.DIFF_REMOVED, nfs4acl.DIFF_ADDED}
+	if len(ops) != len(wantOps) {
+		t.Fatalf("ops %v, want %v", ops, wantOps)
+	}
+	for i := range ops {
+		if ops[i] != wantOps[i] {
+			t.Fatalf("ops %v, want %v", ops, wantOps)
+		}
+	}
+}
+
+func TestDiffACLsEqual(t *testing.T) {
+	acl := nfs4acltest.ACL(t, "A::OWNER@:rw,A:g:GROUP@:r", true)
+	diff := nfs4acl.DiffACLs(acl, acl.Clone())
+	if diff.Changed() || diff.String() != "" {
+		t.Errorf("equal acls differ:\n%s", diff)
+	}
+}
+
+func TestDiffACLsMoved(t *testing.T) {
+	from := nfs4acltest.ACL(t, "D::EVERYONE@:w,A::OWNER@:rw", false)
+	to := nfs4acltest.ACL(t, "A::OWNER@:rw,D::EVERYONE@:w", false)
+	if got, want := nfs4acl.DiffACLs(from, to).String(), "- D::EVERYONE@:w\n+ D::EVERYONE@:w\n"; got != want {
+		t.Errorf("diff\n%s\nwant\n%s", got, want)
+	}
+}
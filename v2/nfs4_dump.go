@@ -0,0 +1,44 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+//PathACL pairs a path with its ACL, as recorded in an nfs4_getfacl dump
+type PathACL struct {
+	Path string
+	ACL  *ACL
+}
+
+//Parses the output of nfs4_getfacl (recursive or not, from this package's
+//tool or the C one) into one PathACL per "# file:" section, in dump order.
+//Sections are read by ParseSpecFile, so other header lines and blank lines
+//are ignored. The dump doesn't say which paths are directories, so the ACLs
+//are parsed with file semantics; the letters map to the same bits either way
+func ParseGetfaclOutput(r io.Reader) ([]PathACL, error) {
+	specs, err := ParseSpecFile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]PathACL, 0, len(specs))
+	for _, spec := range specs {
+		if spec.Path == "" {
+			return nil, errors.New("ace before any \"# file:\" header")
+		}
+		//ParseSpecFile checked the spec already
+		aces, err := ParseACLSpec(spec.Spec, false)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", spec.Path, err)
+		}
+		entries = append(entries, PathACL{Path: spec.Path, ACL: NewACL(false, aces...)})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl_test
+
+import (
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/cclose/libnfs4acl-go/v2/nfs4acltest"
+	"strings"
+	"testing"
+)
+
+const recursiveDump = `# file: /srv
+A:fd:OWNER@:rwaDxtTnNcCoy
+A:fdg:GROUP@:rxtncy
+
+# file: /srv/data.csv
+A::OWNER@:rwatTnNcCoy
+A:g:GROUP@:rtncy
+A::EVERYONE@:rtncy
+`
+
+func TestParseGetfaclOutput(t *testing.T) {
+	entries, err := nfs4acl.ParseGetfaclOutput(strings.NewReader(recursiveDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d entries, want 2", len(entries))
+	}
+	if entries[0].Path != "/srv" || entries[1].Path != "/srv/data.csv" {
+		t.Errorf("paths %q and %q", entries[0].Path, entries[1].Path)
+	}
+	nfs4acltest.AssertEqual(t, entries[0].ACL, nfs4acltest.ACL(t, "A:fd:OWNER@:rwaDxtTnNcCoy,A:fdg:GROUP@:rxtncy", false))
+	nfs4acltest.AssertEqual(t, entries[1].ACL,
+		nfs4acltest.ACL(t, "A::OWNER@:rwatTnNcCoy,A:g:GROUP@:rtncy,A::EVERYONE@:rtncy", false))
+}
+
+func TestParseGetfaclOutputErrors(t *testing.T) {
+	for _, dump := range []string{
+		"A::OWNER@:rw\n# file: /srv\nA::OWNER@:rw\n",
+		"# file: /srv\nA::OWNER@:rw\n# file: /srv/empty\n",
+		"# file: /srv\nA::OWNER@:rwq\n",
+	} {
+		if _, err := nfs4acl.ParseGetfaclOutput(strings.NewReader(dump)); err == nil {
+			t.Errorf("%q accepted", dump)
+		}
+	}
+}
+
+func TestParseSpecFile(t *testing.T) {
+	specs, err := nfs4acl.ParseSpecFile(strings.NewReader("A::OWNER@:rw, \\\n A::EVERYONE@:r\n" + recursiveDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []nfs4acl.PathSpec{
+		{Path: "", Spec: "A::OWNER@:rw, A::EVERYONE@:r"},
+		{Path: "/srv", Spec: "A:fd:OWNER@:rwaDxtTnNcCoy\nA:fdg:GROUP@:rxtncy"},
+		{Path: "/srv/data.csv", Spec: "A::OWNER@:rwatTnNcCoy\nA:g:GROUP@:rtncy\nA::EVERYONE@:rtncy"},
+	}
+	if len(specs) != len(want) {
+		t.Fatalf("got %q", specs)
+	}
+	for i := range want {
+		if specs[i] != want[i] {
+			t.Errorf("section %d is %q, want %q", i, specs[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+//ACERequirement describes an entry that must be present in an ACL. An ACE
+//satisfies it when type, flags and who are equal and its mask includes at
+//least AccessMask
+type ACERequirement struct {
+	AceType    AceType
+	Flags      AceFlags
+	Who        string
+	AccessMask AccessMask
+}
+
+//Ensures every requirement is satisfied with the smallest possible edit:
+//missing bits are added to an existing matching ACE, and otherwise a new ACE
+//is added, denies ahead of the first allow and everything else at the end.
+//With exclusive, ACEs matching no requirement are removed and masks are
+//trimmed to exactly what is required. Reports whether the ACL changed
+func (acl *ACL) Ensure(desired []ACERequirement, exclusive bool) bool {
+	changed := false
+	for _, req := range desired {
+		ace := acl.findRequirement(req)
+		if ace == nil {
+			acl.insertRequirement(req)
+			changed = true
+			continue
+		}
+
+		if exclusive && ace.AccessMask != req.AccessMask {
+			ace.setAccessMask(req.AccessMask)
+			changed = true
+		} else if ace.AccessMask&req.AccessMask != req.AccessMask {
+			ace.applyAccessMask(req.AccessMask)
+			changed = true
+		}
+	}
+
+	if !exclusive {
+		return changed
+	}
+
+	kept := acl.aceList[:0]
+	for _, ace := range acl.aceList {
+		wanted := false
+		for _, req := range desired {
+			if req.matches(&ace) {
+				wanted = true
+				break
+			}
+		}
+		if wanted {
+			kept = append(kept, ace)
+		} else {
+			changed = true
+		}
+	}
+	acl.aceList = kept
+
+	return changed
+}
+
+//Fetches the ACL of path, ensures the requirements as described by
+//ACL.Ensure (exclusively with WithExclusive) and writes it back only if
+//something had to change. Reports whether the ACL was written
+func EnsureACEs(path string, desired []ACERequirement, opts ...Option) (bool, error) {
+	o := newOptions(opts)
+
+	acl, err := GetACL(path, opts...)
+	if err != nil {
+		return false, err
+	}
+	if !acl.Ensure(desired, o.exclusive) {
+		return false, nil
+	}
+
+	return true, SetACL(path, acl, opts...)
+}
+
+//Makes EnsureACEs remove every ACE that isn't required
+func WithExclusive() Option {
+	return func(o *options) {
+		o.exclusive = true
+	}
+}
+
+func (req ACERequirement) matches(ace *ACE) bool {
+	return ace.AceType == req.AceType && ace.Flags == req.Flags && ace.Who == req.Who
+}
+
+//First ACE matching the requirement, regardless of mask
+func (acl *ACL) findRequirement(req ACERequirement) *ACE {
+	for i := range acl.aceList {
+		if req.matches(&acl.aceList[i]) {
+			return &acl.aceList[i]
+		}
+	}
+	return nil
+}
+
+func (acl *ACL) insertRequirement(req ACERequirement) {
+	ace := NewACE(req.AceType, req.Flags, req.AccessMask, req.Who)
+	if req.AceType == NFS4_ACE_ACCESS_DENIED_ACE_TYPE {
+		//a deny after an allow for the same bits would never be reached
+		if acl.InsertBefore(NewACEMatcher().Type(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE), ace) == nil {
+			return
+		}
+	}
+
+	acl.aceList = append(acl.aceList, *ace)
+}
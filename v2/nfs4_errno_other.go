@@ -0,0 +1,14 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+//go:build !(darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package nfs4acl
+
+import (
+	"syscall"
+)
+
+//Error getxattr gives for a missing attribute
+const errNoACL = syscall.ENODATA
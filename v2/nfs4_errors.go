@@ -0,0 +1,40 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"errors"
+	"syscall"
+)
+
+//Reports whether err means the filesystem has no nfs4_acl xattr at all, as
+//on local filesystems. Bulk tools usually skip such paths
+func IsNotSupported(err error) bool {
+	return errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EOPNOTSUPP)
+}
+
+//Reports whether err means the path carries no ACL (ENODATA, ENOATTR on the
+//BSDs and macOS)
+func IsNoACL(err error) bool {
+	return errors.Is(err, errNoACL)
+}
+
+//Reports whether err means the caller may not read or change the ACL
+func IsPermission(err error) bool {
+	return errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EPERM)
+}
+
+//Reports whether err is likely to go away on retrying: an interrupted or
+//would-block call, a stale file handle, or a soft mount timing out
+func IsTransient(err error) bool {
+	return errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.EAGAIN) ||
+		IsStale(err) || errors.Is(err, syscall.ETIMEDOUT)
+}
+
+//Reports whether err means the NFS file handle went stale, typically because
+//the file was replaced on the server. Retrying by path usually succeeds
+func IsStale(err error) bool {
+	return errors.Is(err, syscall.ESTALE)
+}
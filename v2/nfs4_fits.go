@@ -0,0 +1,165 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Mount table consulted to find the server a path is exported from
+const MOUNTINFO_PATH = "/proc/self/mountinfo"
+
+var (
+	serverLimitsMu sync.RWMutex
+	serverLimits   = make(map[string]int)
+)
+
+//Records the largest ACL xattr, in bytes, server accepts, for servers known
+//to take less than the Limits allow. server is the host as it appears in
+//the mount source ("server:/export"). 0 forgets the server
+func SetServerXattrLimit(server string, bytes int) {
+	serverLimitsMu.Lock()
+	defer serverLimitsMu.Unlock()
+	if bytes <= 0 {
+		delete(serverLimits, server)
+		return
+	}
+	serverLimits[server] = bytes
+}
+
+//Returns the largest ACL xattr the mount holding path accepts: the limit set
+//with SetServerXattrLimit for its server if any, the MaxXattrSize of the
+//Limits otherwise. The mount table is only read once a server limit is set,
+//and where there is none to read, as off Linux, the Limits apply. 0 means
+//unlimited
+func MountXattrLimit(path string) (int, error) {
+	serverLimitsMu.RLock()
+	anyServer := len(serverLimits) > 0
+	serverLimitsMu.RUnlock()
+	if !anyServer {
+		return GetLimits().MaxXattrSize, nil
+	}
+
+	server, err := mountServer(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return GetLimits().MaxXattrSize, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	serverLimitsMu.RLock()
+	limit, ok := serverLimits[server]
+	serverLimitsMu.RUnlock()
+	if ok {
+		return limit, nil
+	}
+	return GetLimits().MaxXattrSize, nil
+}
+
+//Checks before any write that acl fits the xattr limit of the mount holding
+//path, so an ACL too large for the server is caught up front rather than when
+//a long recursive operation reaches it. SetACL, and so every operation
+//writing ACLs, checks this first. The failure wraps ErrXattrTooLarge and is
+//also logged as a warning
+func CheckFits(path string, acl *ACL) error {
+	limit, err := MountXattrLimit(path)
+	if err != nil {
+		return err
+	}
+
+	if size := acl.XAttrSize(); limit > 0 && size > limit {
+		currentLogger().Warnf("nfs4acl: acl of %d bytes won't fit %s, the limit is %d", size, path, limit)
+		return fmt.Errorf("%s: %w: %d bytes, the limit is %d", path, ErrXattrTooLarge, size, limit)
+	}
+	return nil
+}
+
+//Finds the server of the NFS mount holding path, or "" if it is not on NFS
+func mountServer(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	f, err := os.Open(MOUNTINFO_PATH)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	//the deepest mount point containing path wins
+	best, server := "", ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		//id parent dev root mountpoint options [optional...] - fstype source superoptions
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 5 || sep < 0 || sep+2 >= len(fields) {
+			continue
+		}
+
+		mountPoint := unescapeMountField(fields[4])
+		if !withinMount(path, mountPoint) || len(mountPoint) < len(best) {
+			continue
+		}
+		best, server = mountPoint, ""
+		if strings.HasPrefix(fields[sep+1], "nfs") {
+			source := fields[sep+2]
+			if colon := strings.Index(source, ":/"); colon >= 0 {
+				server = source[:colon]
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return server, nil
+}
+
+//Reports whether path is mountPoint or below it
+func withinMount(path, mountPoint string) bool {
+	if mountPoint == "/" || path == mountPoint {
+		return true
+	}
+	return strings.HasPrefix(path, mountPoint+"/")
+}
+
+//Decodes the octal escapes (\040 for a space) of mountinfo fields
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) {
+			if n, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//ReadACLFS is a file system that can also read the ACLs of its files, so
+//code written against io/fs can reach them without going back to raw paths
+type ReadACLFS interface {
+	fs.FS
+
+	//Reads the ACL of the named file, with the same name rules as Open
+	ReadACL(name string) (*ACL, error)
+}
+
+//Local directory tree, as returned by DirFS
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+//Returns a file system for the tree rooted at dir, like os.DirFS, that also
+//implements ReadACLFS. Sub keeps the ReadACL support
+func DirFS(dir string) ReadACLFS {
+	return dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+func (f dirFS) ReadACL(name string) (*ACL, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readacl", Path: name, Err: fs.ErrInvalid}
+	}
+
+	path := filepath.Join(f.dir, filepath.FromSlash(name))
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadACL(path, info.IsDir())
+}
+
+//Implements fs.SubFS, so fs.Sub returns a file system still reading ACLs
+func (f dirFS) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	return DirFS(filepath.Join(f.dir, filepath.FromSlash(dir))), nil
+}
+
+//Reads the ACL of name from fsys, which must implement ReadACLFS
+func ReadACL(fsys fs.FS, name string) (*ACL, error) {
+	if aclFS, ok := fsys.(ReadACLFS); ok {
+		return aclFS.ReadACL(name)
+	}
+	return nil, &fs.PathError{Op: "readacl", Path: name, Err: errors.ErrUnsupported}
+}
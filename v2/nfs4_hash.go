@@ -0,0 +1,22 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+//Returns a stable fingerprint of the ACL: the hex encoded SHA-256 of its
+//packed xattr form. Two ACLs hash the same exactly when the server would
+//store the same bytes, so ACE order matters and the file type does not
+func (acl *ACL) Hash() string {
+	buf := getXattrBuffer()
+	defer putXattrBuffer(buf)
+	*buf = acl.packTo(*buf)
+
+	sum := sha256.Sum256(*buf)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//Where nfsidmap reads the NFSv4 domain from
+const DEFAULT_IDMAPD_CONF = "/etc/idmapd.conf"
+
+//IDMapper maps local uids and gids to the user@domain who strings an NFSv4
+//server expects
+type IDMapper struct {
+	//NFSv4 domain appended to names, may be changed after construction
+	Domain string
+	//Produce plain uids and gids instead of names, for servers with
+	//idmapping disabled
+	Numeric bool
+}
+
+//Creates an IDMapper using the domain configured in /etc/idmapd.conf
+func NewIDMapper() (*IDMapper, error) {
+	return NewIDMapperFromConfig(DEFAULT_IDMAPD_CONF)
+}
+
+//Creates an IDMapper using the domain configured in the idmapd.conf at path.
+//As with nfsidmap, a missing file or Domain setting falls back to the DNS
+//domain of the host
+func NewIDMapperFromConfig(path string) (*IDMapper, error) {
+	domain, err := ReadIDMapDomain(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if domain == "" {
+		if domain, err = hostDomain(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &IDMapper{Domain: domain}, nil
+}
+
+//Returns the Domain setting of the [General] section of an idmapd.conf, or ""
+//if there is none
+func ReadIDMapDomain(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.EqualFold(section, "General") && strings.EqualFold(strings.TrimSpace(key), "Domain") {
+			return strings.TrimSpace(value), nil
+		}
+	}
+
+	return "", scanner.Err()
+}
+
+//The host name without its first label, nfsidmap's default domain
+func hostDomain() (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	if dot := strings.Index(host, "."); dot >= 0 && dot < len(host)-1 {
+		return host[dot+1:], nil
+	}
+	return "", errors.New("no nfsv4 domain configured and the host name has none")
+}
+
+//Returns the who string for uid, e.g. "bob@example.com"
+func (m *IDMapper) UserWho(uid uint32) (string, error) {
+	if m.Numeric {
+		return strconv.FormatUint(uint64(uid), 10), nil
+	}
+	name, err := GetPrincipalResolver().UserName(uid)
+	if err != nil {
+		return "", err
+	}
+
+	return m.qualify(name), nil
+}
+
+//Returns the who string for gid, e.g. "staff@example.com". ACEs using it need
+//the IDENTIFIER_GROUP flag
+func (m *IDMapper) GroupWho(gid uint32) (string, error) {
+	if m.Numeric {
+		return strconv.FormatUint(uint64(gid), 10), nil
+	}
+	name, err := GetPrincipalResolver().GroupName(gid)
+	if err != nil {
+		return "", err
+	}
+
+	return m.qualify(name), nil
+}
+
+func (m *IDMapper) qualify(name string) string {
+	if m.Domain == "" {
+		return name
+	}
+	return fmt.Sprintf("%s@%s", name, m.Domain)
+}
+
+//Translates a numeric who into a name for display, e.g. "1000" to "bob". The
+//IDENTIFIER_GROUP flag decides whether it is looked up as a gid, so isGroup
+//must come from the ACE. With a mapper the name is qualified with its
+//domain. Other whos and ids that can't be resolved are returned unchanged
+func ResolveNumericWho(who string, isGroup bool, mapper *IDMapper) string {
+	if !IsNumericWho(who) {
+		return who
+	}
+	id, err := strconv.ParseUint(who, 10, 32)
+	if err != nil {
+		return who
+	}
+
+	if mapper == nil {
+		mapper = &IDMapper{}
+	} else if mapper.Numeric {
+		return who
+	}
+
+	var name string
+	if isGroup {
+		name, err = mapper.GroupWho(uint32(id))
+	} else {
+		name, err = mapper.UserWho(uint32(id))
+	}
+	if err != nil {
+		return who
+	}
+	return name
+}
+
+//Returns a copy of the ACL with every numeric who translated by
+//ResolveNumericWho
+func (acl *ACL) WithResolvedWhos(mapper *IDMapper) *ACL {
+	clone := acl.Clone()
+	for i := range clone.aceList {
+		ace := &clone.aceList[i]
+		ace.Who = ResolveNumericWho(ace.Who, ace.Flags&NFS4_ACE_IDENTIFIER_GROUP != 0, mapper)
+		ace.WhoType = AceGetWhoType(ace.Who)
+	}
+
+	return clone
+}
+
+//Translates a named who into the local numeric uid, or gid when isGroup is
+//set, e.g. "bob@example.com" to "1000". Special whos and names that can't be
+//resolved are returned unchanged
+func NumericWho(who string, isGroup bool) string {
+	if AceGetWhoType(who) != NFS4_ACL_WHO_NAMED {
+		return who
+	}
+
+	//drop the NFSv4 domain, local lookups only know the bare name
+	name := bareWho(who)
+
+	var id uint32
+	var err error
+	if isGroup {
+		id, err = GetPrincipalResolver().LookupGroup(name)
+	} else {
+		id, err = GetPrincipalResolver().LookupUser(name)
+	}
+	if err != nil {
+		return who
+	}
+
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+//Returns a copy of the ACL with every named who translated by NumericWho,
+//for display on clients where names can't be trusted
+func (acl *ACL) WithNumericWhos() *ACL {
+	clone := acl.Clone()
+	for i := range clone.aceList {
+		ace := &clone.aceList[i]
+		ace.Who = NumericWho(ace.Who, ace.Flags&NFS4_ACE_IDENTIFIER_GROUP != 0)
+		ace.WhoType = AceGetWhoType(ace.Who)
+	}
+
+	return clone
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+//All flags that control inheritance
+const NFS4_ACE_INHERITANCE_FLAGS = NFS4_ACE_FILE_INHERIT_ACE | NFS4_ACE_DIRECTORY_INHERIT_ACE |
+	NFS4_ACE_NO_PROPAGATE_INHERIT_ACE | NFS4_ACE_INHERIT_ONLY_ACE
+
+//Computes the ACL a new file or directory created in a directory with the
+//parent ACL receives, following RFC 5661 section 6.4.3:
+//
+// - files get every FILE_INHERIT ACE, with all inheritance flags cleared
+// - directories get every DIRECTORY_INHERIT ACE, effective on the directory
+//   and still inheritable unless NO_PROPAGATE_INHERIT is set
+// - directories keep FILE_INHERIT only ACEs as INHERIT_ONLY, so they reach the
+//   files further down, unless NO_PROPAGATE_INHERIT is set
+//
+//Every ACE of the result carries INHERITED_ACE, as NFSv4.1 servers mark them
+func InheritedACL(parent *ACL, childIsDir bool) *ACL {
+	child := &ACL{
+		isDirectory: childIsDir,
+	}
+
+	for _, ace := range parent.aceList {
+		flags := ace.Flags
+		fileInherit := flags&NFS4_ACE_FILE_INHERIT_ACE != 0
+		dirInherit := flags&NFS4_ACE_DIRECTORY_INHERIT_ACE != 0
+		noPropagate := flags&NFS4_ACE_NO_PROPAGATE_INHERIT_ACE != 0
+
+		switch {
+		case !childIsDir && fileInherit:
+			flags &^= NFS4_ACE_INHERITANCE_FLAGS
+		case childIsDir && dirInherit && noPropagate:
+			flags &^= NFS4_ACE_INHERITANCE_FLAGS
+		case childIsDir && dirInherit:
+			flags &^= NFS4_ACE_INHERIT_ONLY_ACE
+		case childIsDir && fileInherit && !noPropagate:
+			flags |= NFS4_ACE_INHERIT_ONLY_ACE
+		default:
+			continue
+		}
+		flags |= NFS4_ACE_INHERITED_ACE
+
+		child.aceList = append(child.aceList, *NewACE(ace.AceType, flags, ace.AccessMask, ace.Who))
+	}
+
+	return child
+}
+
+//Replaces the ACL of every descendant of the directory root with the ACL it
+//would inherit, as computed by InheritedACL, starting from root's current
+//ACL. This is the equivalent of "replace all child permission entries" on
+//Windows. Descendants that would inherit nothing are left untouched, rather
+//than being locked out with an empty ACL. Directories left out by the
+//filters of WithWalkOptions keep their ACL, but their children still get what
+//they would inherit through them. With WithZFS, the aclinherit of each
+//directory's dataset applies
+func PropagateInheritance(root string, opts ...Option) error {
+	return PropagateInheritanceContext(context.Background(), root, opts...)
+}
+
+//Same as PropagateInheritance, but stops as soon as ctx is done and returns
+//ctx.Err(). Descendants already rewritten keep their new ACL
+func PropagateInheritanceContext(ctx context.Context, root string, opts ...Option) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("inheritance can only be propagated from a directory")
+	}
+
+	rootACL, err := LoadACL(root, true, opts...)
+	if err != nil {
+		return err
+	}
+
+	o := newOptions(opts)
+	prepare := inheritPreparer(root, rootACL, o)
+
+	return runBulk(ctx, root, o, prepare, func(job applyJob) (*ACL, int, error) {
+		acl, err := setInheritedACL(ctx, job.path, job.acl, opts...)
+		if err != nil {
+			return nil, 0, err
+		}
+		return acl, acl.XAttrSize(), nil
+	})
+}
+
+//Returns the runBulk prepare step of PropagateInheritance, which sets each
+//job's ACL to what it inherits and skips jobs that inherit nothing. The walk
+//visits a parent before its children, but the directories its filters leave
+//out (FilesOnly, Include) are never seen, so their ACLs are computed on demand
+//from the nearest ancestor, without being written
+func inheritPreparer(root string, rootACL *ACL, o *options) func(job *applyJob) (bool, error) {
+	root = filepath.Clean(root)
+	//ACLs computed for the directories seen so far
+	computed := map[string]*ACL{
+		root: rootACL,
+	}
+	//with WithZFS, the properties of each directory seen so far
+	zfsProps := make(map[string]*ZFSProperties)
+
+	var dirACL func(dir string) (*ACL, error)
+	dirACL = func(dir string) (*ACL, error) {
+		if acl, ok := computed[dir]; ok {
+			return acl, nil
+		}
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return nil, fmt.Errorf("%s is not below %s", dir, root)
+		}
+		parent, err := dirACL(parentDir)
+		if err != nil {
+			return nil, err
+		}
+		acl, err := inheritedACLOf(parentDir, parent, true, o, zfsProps)
+		if err != nil {
+			return nil, err
+		}
+		computed[dir] = acl
+		return acl, nil
+	}
+
+	return func(job *applyJob) (bool, error) {
+		path := filepath.Clean(job.path)
+		if path == root {
+			return true, nil
+		}
+
+		parentDir := filepath.Dir(path)
+		parent, err := dirACL(parentDir)
+		if err != nil {
+			return false, err
+		}
+		acl, err := inheritedACLOf(parentDir, parent, job.isDir, o, zfsProps)
+		if err != nil {
+			return false, err
+		}
+		job.acl = acl
+		if job.isDir {
+			computed[path] = job.acl
+		}
+
+		return len(job.acl.aceList) == 0, nil
+	}
+}
+
+//Computes the ACL path would receive if it were created now, from the
+//inheritable ACEs of its parent directory. With WithZFS, as the aclinherit of
+//the parent's dataset has it
+func InheritedACLForPath(path string, opts ...Option) (*ACL, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	parentDir := filepath.Dir(filepath.Clean(path))
+	if parentDir == filepath.Clean(path) {
+		return nil, errors.New("path has no parent directory")
+	}
+	parent, err := LoadACL(parentDir, true, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return inheritedACLOf(parentDir, parent, info.IsDir(), newOptions(opts), nil)
+}
+
+//Computes the ACL a child of parentDir, whose ACL is parent, inherits. With
+//WithZFS the dataset's aclinherit decides, its properties looked up once per
+//directory when cache is given
+func inheritedACLOf(parentDir string, parent *ACL, childIsDir bool, o *options, cache map[string]*ZFSProperties) (*ACL, error) {
+	if !o.zfs {
+		return InheritedACL(parent, childIsDir), nil
+	}
+
+	props := cache[parentDir]
+	if props == nil {
+		var err error
+		if props, err = ZFSPropertiesForPath(parentDir); err != nil {
+			return nil, err
+		}
+		if cache != nil {
+			cache[parentDir] = props
+		}
+	}
+	return props.InheritedACL(parent, childIsDir), nil
+}
+
+//Discards the explicit ACEs of path and replaces them with those it inherits
+//from its parent directory. Fails rather than writing an empty ACL when the
+//parent has nothing inheritable
+func ResetToInherited(path string, opts ...Option) error {
+	acl, err := InheritedACLForPath(path, opts...)
+	if err != nil {
+		return err
+	}
+	if len(acl.aceList) == 0 {
+		return errors.New("parent directory has no inheritable ACEs")
+	}
+
+	_, err = setInheritedACL(context.Background(), path, acl, opts...)
+	return err
+}
+
+//Writes an ACL computed by InheritedACL. NFSv4.0 servers such as knfsd reject
+//INHERITED_ACE with EINVAL, so it's retried once without the flag. Returns
+//the ACL written
+func setInheritedACL(ctx context.Context, path string, acl *ACL, opts ...Option) (*ACL, error) {
+	err := SetACLContext(ctx, path, acl, opts...)
+	if !errors.Is(err, syscall.EINVAL) {
+		return acl, err
+	}
+
+	plain := acl.Clone()
+	for i := range plain.aceList {
+		ace := &plain.aceList[i]
+		ace.RemoveFlags(NFS4_ACE_INHERITED_ACE)
+	}
+	if err := SetACLContext(ctx, path, plain, opts...); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+//Prepares an ACL written for a directory to be applied to a regular file:
+//INHERIT_ONLY ACEs are removed, and inheritance flags are cleared from the
+//rest, since some servers reject them on files. The ACL becomes a file ACL
+func (acl *ACL) StripInheritOnly() {
+	kept := acl.aceList[:0]
+	for _, ace := range acl.aceList {
+		if ace.Flags&NFS4_ACE_INHERIT_ONLY_ACE != 0 {
+			continue
+		}
+		ace.Flags &^= NFS4_ACE_INHERITANCE_FLAGS
+		kept = append(kept, ace)
+	}
+
+	acl.aceList = kept
+	acl.isDirectory = false
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInheritPreparerFilesOnly(t *testing.T) {
+	root := t.TempDir()
+	deep := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(root, "top"), filepath.Join(deep, "deep")} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rootACL := specACL(t, "A:fdi:OWNER@:rw,A:fi:alice@example.com:r,A:di:bob@example.com:x", true)
+	o := newOptions([]Option{WithWalkOptions(WalkOptions{FilesOnly: true})})
+	prepare := inheritPreparer(root, rootACL, o)
+
+	written := make(map[string]*ACL)
+	err := walkPaths(context.Background(), root, o.walk, nopLogger{}, func(path string, info fs.FileInfo, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		job := applyJob{path: path, isDir: isDir, info: info}
+		skip, err := prepare(&job)
+		if err != nil {
+			return err
+		}
+		if !skip {
+			written[path] = job.acl
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(written) != 2 {
+		t.Fatalf("wrote %d ACLs, want 2: %v", len(written), written)
+	}
+	//the file below the skipped directories inherits through them
+	want := specACL(t, "A:I:OWNER@:rw,A:I:alice@example.com:r", false)
+	for _, path := range []string{filepath.Join(root, "top"), filepath.Join(deep, "deep")} {
+		acl, ok := written[path]
+		if !ok {
+			t.Errorf("%s skipped", path)
+		} else if acl.Hash() != want.Hash() {
+			t.Errorf("%s gets %v, want %v", path, acl.ACEs(), want.ACEs())
+		}
+	}
+}
+
+//Builds an ACL from spec, nfs4acltest.ACL can't be used from inside the
+//package
+func specACL(t *testing.T, spec string, isDir bool) *ACL {
+	t.Helper()
+	aces, err := ParseACLSpec(spec, isDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewACL(isDir, aces...)
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl_test
+
+import (
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/cclose/libnfs4acl-go/v2/nfs4acltest"
+	"testing"
+)
+
+//One ACE for every combination of the inheritance flags
+const inheritParent = "A::OWNER@:rw," +
+	"A:f:alice@example.com:r," +
+	"A:d:bob@example.com:r," +
+	"A:fd:carol@example.com:r," +
+	"A:fi:dave@example.com:r," +
+	"A:fdn:erin@example.com:r," +
+	"A:fn:frank@example.com:r"
+
+func TestInheritedACLFile(t *testing.T) {
+	parent := nfs4acltest.ACL(t, inheritParent, true)
+	nfs4acltest.AssertEqual(t, nfs4acl.InheritedACL(parent, false), nfs4acltest.ACL(t,
+		"A:I:alice@example.com:r,"+
+			"A:I:carol@example.com:r,"+
+			"A:I:dave@example.com:r,"+
+			"A:I:erin@example.com:r,"+
+			"A:I:frank@example.com:r", false))
+}
+
+func TestInheritedACLDirectory(t *testing.T) {
+	parent := nfs4acltest.ACL(t, inheritParent, true)
+	nfs4acltest.AssertEqual(t, nfs4acl.InheritedACL(parent, true), nfs4acltest.ACL(t,
+		"A:fiI:alice@example.com:r,"+
+			"A:dI:bob@example.com:r,"+
+			"A:fdI:carol@example.com:r,"+
+			"A:fiI:dave@example.com:r,"+
+			"A:I:erin@example.com:r", true))
+}
+
+func TestInheritedACLKeepsParent(t *testing.T) {
+	parent := nfs4acltest.ACL(t, inheritParent, true)
+	nfs4acl.InheritedACL(parent, false)
+	nfs4acltest.AssertEqual(t, parent, nfs4acltest.ACL(t, inheritParent, true))
+}
+
+func TestStripInheritOnly(t *testing.T) {
+	acl := nfs4acltest.ACL(t, "A:fdi:OWNER@:rw,A:fd:GROUP@:r,A::EVERYONE@:r", true)
+	acl.StripInheritOnly()
+	nfs4acltest.AssertEqual(t, acl, nfs4acltest.ACL(t, "A::GROUP@:r,A::EVERYONE@:r", false))
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"strings"
+	"sync"
+)
+
+//WhoInterner hands out a single shared copy of every who it has seen, so
+//scans keeping millions of ACLs in memory hold each principal name once
+//rather than once per ACE. It never forgets a who; use one per scan and drop
+//it afterwards. Safe for concurrent use
+type WhoInterner struct {
+	mu   sync.RWMutex
+	whos map[string]string
+}
+
+//Creates an empty interner, to be installed with SetWhoInterner
+func NewWhoInterner() *WhoInterner {
+	return &WhoInterner{
+		whos: make(map[string]string),
+	}
+}
+
+//Returns the shared copy of who, storing who if it is new
+func (in *WhoInterner) Intern(who string) string {
+	in.mu.RLock()
+	shared, ok := in.whos[who]
+	in.mu.RUnlock()
+	if ok {
+		return shared
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if shared, ok = in.whos[who]; !ok {
+		//who may be a slice of a whole xattr, don't keep that alive
+		shared = strings.Clone(who)
+		in.whos[shared] = shared
+	}
+	return shared
+}
+
+//Number of distinct whos held
+func (in *WhoInterner) Len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.whos)
+}
+
+var (
+	internerMu      sync.RWMutex
+	packageInterner *WhoInterner
+)
+
+//Makes XAttrLoad, and so LoadACL, intern every who it decodes through in.
+//nil, the default, turns interning off
+func SetWhoInterner(in *WhoInterner) {
+	internerMu.Lock()
+	packageInterner = in
+	internerMu.Unlock()
+}
+
+func currentWhoInterner() *WhoInterner {
+	internerMu.RLock()
+	defer internerMu.RUnlock()
+	return packageInterner
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+//JSON form of an ACE. The fields use the spec letters, with the raw mask kept
+//alongside since perms alone can't tell the file and directory bits apart
+type aceJSON struct {
+	Type  string     `json:"type"`
+	Flags string     `json:"flags"`
+	Who   string     `json:"who"`
+	Perms string     `json:"perms"`
+	Mask  AccessMask `json:"mask"`
+}
+
+//JSON form of an ACL
+type aclJSON struct {
+	Directory bool      `json:"directory"`
+	ACEs      []aceJSON `json:"aces"`
+}
+
+func newACEJSON(ace *ACE, isDir bool) aceJSON {
+	return aceJSON{
+		Type:  AceTypeString(ace.AceType, false),
+		Flags: FlagsString(ace.Flags),
+		Who:   ace.Who,
+		Perms: PermsString(ace.AccessMask, isDir),
+		Mask:  ace.AccessMask,
+	}
+}
+
+//Encodes the ACE as an object with type, flags, who and perms in spec letters
+//plus the numeric mask. Perms are given with file semantics
+func (ace *ACE) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newACEJSON(ace, false))
+}
+
+//Builds the ACE described by the JSON form. The mask wins over perms when
+//both are given, as it keeps bits perms can't express
+func (in aceJSON) ace(isDir bool) (*ACE, error) {
+	aceType, err := ParseAceType(in.Type)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := ParseFlags(in.Flags)
+	if err != nil {
+		return nil, err
+	}
+	//as in ParseACE, the pseudo flags are derived from the who
+	if flags&(NFS4_ACE_OWNER|NFS4_ACE_GROUP|NFS4_ACE_EVERYONE) != 0 {
+		return nil, fmt.Errorf("flags %q: O, G and E flags can't be set directly", in.Flags)
+	}
+	who := normalizeWho(in.Who)
+	if err = ValidateWho(who); err != nil {
+		return nil, err
+	}
+	mask := in.Mask
+	if mask == 0 {
+		if mask, err = parsePerms(in.Perms, isDir); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewACE(aceType, flags, mask, who), nil
+}
+
+//Decodes the form written by MarshalJSON
+func (ace *ACE) UnmarshalJSON(data []byte) error {
+	var in aceJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	decoded, err := in.ace(false)
+	if err != nil {
+		return err
+	}
+	*ace = *decoded
+	return nil
+}
+
+//Encodes the ACL as an object holding whether it belongs to a directory and
+//the list of its ACEs, in order
+func (acl *ACL) MarshalJSON() ([]byte, error) {
+	out := aclJSON{
+		Directory: acl.isDirectory,
+		ACEs:      make([]aceJSON, 0, len(acl.aceList)),
+	}
+	for i := range acl.aceList {
+		out.ACEs = append(out.ACEs, newACEJSON(&acl.aceList[i], acl.isDirectory))
+	}
+
+	return json.Marshal(out)
+}
+
+//Decodes the form written by MarshalJSON, replacing the ACL's contents
+func (acl *ACL) UnmarshalJSON(data []byte) error {
+	var in aclJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	aces := make([]ACE, 0, len(in.ACEs))
+	for i, entry := range in.ACEs {
+		ace, err := entry.ace(in.Directory)
+		if err != nil {
+			return fmt.Errorf("ace %d: %w", i+1, err)
+		}
+		aces = append(aces, *ace)
+	}
+
+	acl.isDirectory = in.Directory
+	acl.aceList = aces
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl_test
+
+import (
+	"encoding/json"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/cclose/libnfs4acl-go/v2/nfs4acltest"
+	"testing"
+)
+
+func TestACLJSONRoundTrip(t *testing.T) {
+	for _, isDir := range []bool{false, true} {
+		want := nfs4acltest.ACL(t, "A:fdi:OWNER@:rwaDxtcy,D:g:staff@example.com:w,A:I:EVERYONE@:r", isDir)
+		data, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got nfs4acl.ACL
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		nfs4acltest.AssertEqual(t, &got, want)
+	}
+}
+
+func TestACLJSONRejects(t *testing.T) {
+	for _, data := range []string{
+		`{"directory":false,"aces":[{"type":"A","flags":"O","who":"OWNER@","perms":"r"}]}`,
+		`{"directory":false,"aces":[{"type":"A","flags":"fE","who":"EVERYONE@","perms":"r"}]}`,
+		`{"directory":false,"aces":[{"type":"Q","flags":"","who":"OWNER@","perms":"r"}]}`,
+		`{"directory":false,"aces":[{"type":"A","flags":"","who":"","perms":"r"}]}`,
+	} {
+		var acl nfs4acl.ACL
+		if err := json.Unmarshal([]byte(data), &acl); err == nil {
+			t.Errorf("%s accepted", data)
+		}
+	}
+
+	var ace nfs4acl.ACE
+	if err := json.Unmarshal([]byte(`{"type":"A","flags":"G","who":"GROUP@","perms":"r"}`), &ace); err == nil {
+		t.Error("ace with a pseudo flag accepted")
+	}
+}
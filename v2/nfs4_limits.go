@@ -0,0 +1,67 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+//Default limits, in line with the Linux NFS server (NFSD4_ACL_MAX) and the
+//kernel's cap on the size of a single xattr (XATTR_SIZE_MAX)
+const (
+	DEFAULT_MAX_ACES       = 1024
+	DEFAULT_MAX_XATTR_SIZE = 65536
+)
+
+//Returned, wrapped with the actual figures, by ACLs beyond the Limits
+var (
+	ErrTooManyACEs   = errors.New("too many aces")
+	ErrXattrTooLarge = errors.New("acl xattr too large")
+)
+
+//Limits bounds the ACLs XAttrLoad accepts and PackXAttr, and so SetACL,
+//produces, so oversized ACLs fail with a clear error instead of an errno
+//from the server. Zero disables a limit
+type Limits struct {
+	MaxACEs      int
+	MaxXattrSize int //bytes
+}
+
+var (
+	limitsMu      sync.RWMutex
+	packageLimits = Limits{
+		MaxACEs:      DEFAULT_MAX_ACES,
+		MaxXattrSize: DEFAULT_MAX_XATTR_SIZE,
+	}
+)
+
+//Sets the limits enforced from then on, e.g. to match a server allowing
+//fewer ACEs
+func SetLimits(limits Limits) {
+	limitsMu.Lock()
+	packageLimits = limits
+	limitsMu.Unlock()
+}
+
+//Returns the limits set by SetLimits
+func GetLimits() Limits {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	return packageLimits
+}
+
+//Checks an ACL of numACEs ACEs taking xattrSize bytes against the limits
+func (l Limits) check(numACEs, xattrSize int) error {
+	if l.MaxACEs > 0 && numACEs > l.MaxACEs {
+		return fmt.Errorf("%w: %d, the limit is %d", ErrTooManyACEs, numACEs, l.MaxACEs)
+	}
+	if l.MaxXattrSize > 0 && xattrSize > l.MaxXattrSize {
+		return fmt.Errorf("%w: %d bytes, the limit is %d", ErrXattrTooLarge, xattrSize, l.MaxXattrSize)
+	}
+
+	return nil
+}
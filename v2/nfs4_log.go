@@ -0,0 +1,59 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"sync"
+)
+
+//Logger receives diagnostics from library internals, such as skipped paths,
+//handled failures and malformed xattrs. The standard *log.Logger does not
+//satisfy it directly, wrap it in a type with these two methods
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+
+var (
+	loggerMu      sync.RWMutex
+	packageLogger Logger = nopLogger{}
+)
+
+//Sets the logger used when an operation has none of its own (see WithLogger).
+//nil silences the library, which is the default
+func SetLogger(logger Logger) {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	loggerMu.Lock()
+	packageLogger = logger
+	loggerMu.Unlock()
+}
+
+//Sets the logger for a single operation, overriding SetLogger
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+func currentLogger() Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return packageLogger
+}
+
+//Logger of an operation, falling back to the package logger
+func (o *options) log() Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return currentLogger()
+}
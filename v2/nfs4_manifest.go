@@ -0,0 +1,199 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+)
+
+//Format version written by ExportManifest
+const MANIFEST_VERSION = 1
+
+//Manifest records the ACL of every path of a tree, independently of the file
+//data, as written by ExportManifest
+type Manifest struct {
+	Version int             `json:"version"`
+	Entries []ManifestEntry `json:"entries"`
+}
+
+//ManifestEntry is the recorded ACL of one path
+type ManifestEntry struct {
+	Path string `json:"path"` //relative to the root, "." for the root itself
+	Hash string `json:"hash"` //see ACL.Hash
+	ACL  *ACL   `json:"acl"`
+}
+
+//Kinds of Drift
+const (
+	DRIFT_CHANGED = "changed" //the ACL differs from the manifest
+	DRIFT_MISSING = "missing" //recorded in the manifest, not found in the tree
+	DRIFT_EXTRA   = "extra"   //found in the tree, not recorded in the manifest
+	DRIFT_ERROR   = "error"   //the ACL could not be read
+)
+
+//Drift is a difference between a tree and its manifest, as found by
+//VerifyManifest
+type Drift struct {
+	Path     string //relative to the root
+	Kind     string
+	Expected *ACL  //from the manifest, nil for DRIFT_EXTRA
+	Actual   *ACL  //from the tree, nil for DRIFT_MISSING and DRIFT_ERROR
+	Err      error //set for DRIFT_ERROR
+}
+
+//Writes a JSON manifest of the ACL of every path under root to w, in walk
+//order. Traversal follows WithWalkOptions, and paths whose ACL can't be read
+//go to the WithErrorHandler handler, aborting the export without one
+func ExportManifest(root string, w io.Writer, opts ...Option) error {
+	o := newOptions(opts)
+	manifest := Manifest{
+		Version: MANIFEST_VERSION,
+		Entries: []ManifestEntry{},
+	}
+
+	err := walkPaths(context.Background(), root, o.walk, o.log(), func(path string, info fs.FileInfo, isDir bool, err error) error {
+		var acl *ACL
+		if err == nil {
+			acl, err = LoadACL(path, isDir, opts...)
+		}
+		if err != nil {
+			if o.onError != nil {
+				return o.onError(path, err)
+			}
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		manifest.Entries = append(manifest.Entries, ManifestEntry{Path: rel, Hash: acl.Hash(), ACL: acl})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(manifest)
+}
+
+//Reads a manifest written by ExportManifest, checking every entry against
+//its hash
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version != MANIFEST_VERSION {
+		return nil, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+
+	for _, entry := range manifest.Entries {
+		if entry.ACL == nil {
+			return nil, fmt.Errorf("%s: no acl in manifest entry", entry.Path)
+		}
+		if entry.ACL.Hash() != entry.Hash {
+			return nil, fmt.Errorf("%s: acl doesn't match its hash", entry.Path)
+		}
+	}
+
+	return &manifest, nil
+}
+
+//Reapplies the manifest read from r to the tree rooted at root, which need
+//not be the root it was exported from. Only ACLs that differ from the
+//manifest are written. Failures go to the WithErrorHandler handler, aborting
+//the restore without one
+func RestoreManifest(root string, r io.Reader, opts ...Option) error {
+	manifest, err := ReadManifest(r)
+	if err != nil {
+		return err
+	}
+
+	o := newOptions(opts)
+	for _, entry := range manifest.Entries {
+		path := filepath.Join(root, entry.Path)
+		current, err := LoadACL(path, entry.ACL.isDirectory, opts...)
+		if err == nil && current.Hash() == entry.Hash {
+			continue
+		}
+
+		if err = SetACL(path, entry.ACL, opts...); err != nil {
+			if o.onError == nil {
+				return err
+			}
+			if err = o.onError(path, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+//Compares the tree rooted at root against the manifest read from r without
+//changing anything, returning every difference in walk order, followed by
+//the paths missing from the tree. The walk should use the WithWalkOptions
+//the manifest was exported with, or the paths left out show up as drift
+func VerifyManifest(root string, r io.Reader, opts ...Option) ([]Drift, error) {
+	manifest, err := ReadManifest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	o := newOptions(opts)
+	expected := make(map[string]*ManifestEntry, len(manifest.Entries))
+	for i := range manifest.Entries {
+		expected[manifest.Entries[i].Path] = &manifest.Entries[i]
+	}
+	seen := make(map[string]bool, len(manifest.Entries))
+
+	var drift []Drift
+	err = walkPaths(context.Background(), root, o.walk, o.log(), func(path string, info fs.FileInfo, isDir bool, err error) error {
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return relErr
+		}
+		seen[rel] = true
+
+		entry := expected[rel]
+		var acl *ACL
+		if err == nil {
+			acl, err = LoadACL(path, isDir, opts...)
+		}
+
+		switch {
+		case err != nil:
+			found := Drift{Path: rel, Kind: DRIFT_ERROR, Err: err}
+			if entry != nil {
+				found.Expected = entry.ACL
+			}
+			drift = append(drift, found)
+		case entry == nil:
+			drift = append(drift, Drift{Path: rel, Kind: DRIFT_EXTRA, Actual: acl})
+		case acl.Hash() != entry.Hash:
+			drift = append(drift, Drift{Path: rel, Kind: DRIFT_CHANGED, Expected: entry.ACL, Actual: acl})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range manifest.Entries {
+		if !seen[entry.Path] {
+			drift = append(drift, Drift{Path: entry.Path, Kind: DRIFT_MISSING, Expected: entry.ACL})
+		}
+	}
+
+	return drift, nil
+}
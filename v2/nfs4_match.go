@@ -0,0 +1,115 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"errors"
+)
+
+//ACEMatcher selects ACEs by any combination of type, who, who type, flags
+//and mask bits. Criteria are added with the chainable setters, e.g.
+//
+//	NewACEMatcher().Type(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE).Who("EVERYONE@")
+//
+//A matcher without criteria matches every ACE
+type ACEMatcher struct {
+	aceType    AceType
+	hasType    bool
+	who        string
+	hasWho     bool
+	whoType    uint
+	hasWhoType bool
+	flags      AceFlags   //all of these must be set
+	mask       AccessMask //all of these must be set
+}
+
+//Creates a matcher matching every ACE
+func NewACEMatcher() *ACEMatcher {
+	return &ACEMatcher{}
+}
+
+//Matches ACEs of the given type
+func (m *ACEMatcher) Type(aceType AceType) *ACEMatcher {
+	m.aceType, m.hasType = aceType, true
+	return m
+}
+
+//Matches ACEs whose who is exactly who
+func (m *ACEMatcher) Who(who string) *ACEMatcher {
+	m.who, m.hasWho = who, true
+	return m
+}
+
+//Matches ACEs of the given who type, e.g. NFS4_ACL_WHO_EVERYONE
+func (m *ACEMatcher) WhoType(whoType uint) *ACEMatcher {
+	m.whoType, m.hasWhoType = whoType, true
+	return m
+}
+
+//Matches ACEs having at least the given flags
+func (m *ACEMatcher) Flags(flags AceFlags) *ACEMatcher {
+	m.flags |= flags
+	return m
+}
+
+//Matches ACEs granting or denying at least the given mask bits
+func (m *ACEMatcher) Mask(accessMask AccessMask) *ACEMatcher {
+	m.mask |= accessMask
+	return m
+}
+
+//Reports whether ace meets every criterion of the matcher
+func (m *ACEMatcher) Matches(ace *ACE) bool {
+	return (!m.hasType || ace.AceType == m.aceType) &&
+		(!m.hasWho || ace.Who == m.who) &&
+		(!m.hasWhoType || ace.WhoType == m.whoType) &&
+		ace.Flags&m.flags == m.flags &&
+		ace.AccessMask&m.mask == m.mask
+}
+
+//Returns the 0-based indexes of every ACE matching m, in order
+func (acl *ACL) Find(m *ACEMatcher) []int {
+	var indexes []int
+	for i := range acl.aceList {
+		if m.Matches(&acl.aceList[i]) {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
+
+//Returns a copy of the first ACE matching m and its 0-based index, or nil
+//and -1
+func (acl *ACL) First(m *ACEMatcher) (*ACE, int) {
+	for i := range acl.aceList {
+		if m.Matches(&acl.aceList[i]) {
+			aceCopy := acl.aceList[i]
+			return &aceCopy, i
+		}
+	}
+
+	return nil, -1
+}
+
+//Inserts ace right before the first ACE matching m, e.g. a deny ahead of the
+//EVERYONE@ allow. Fails, leaving the ACL alone, if no ACE matches
+func (acl *ACL) InsertBefore(m *ACEMatcher, ace *ACE) error {
+	_, i := acl.First(m)
+	if i < 0 {
+		return errors.New("no matching ace")
+	}
+	return acl.InsertACE(i, ace)
+}
+
+//Inserts ace right after the first ACE matching m. Fails, leaving the ACL
+//alone, if no ACE matches
+func (acl *ACL) InsertAfter(m *ACEMatcher, ace *ACE) error {
+	_, i := acl.First(m)
+	if i < 0 {
+		return errors.New("no matching ace")
+	}
+	return acl.InsertACE(i+1, ace)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+//Classes of errors reported to Metrics, see ErrorClass
+const (
+	ERROR_CLASS_NOT_SUPPORTED = "not_supported"
+	ERROR_CLASS_NO_ACL        = "no_acl"
+	ERROR_CLASS_PERMISSION    = "permission"
+	ERROR_CLASS_STALE         = "stale"
+	ERROR_CLASS_TOO_LARGE     = "too_large"
+	ERROR_CLASS_OTHER         = "other"
+)
+
+//Metrics receives measurements from the library, for export to a monitoring
+//system (see the nfs4_prometheus package). Methods are called concurrently
+//and should not block
+type Metrics interface {
+	//An ACL of size bytes was read
+	ACLRead(size int)
+	//An ACL of size bytes was written
+	ACLWritten(size int)
+	//Reading or writing an ACL failed, class is one of the ERROR_CLASS values
+	Error(class string)
+	//A walk of a tree, by WalkACLs or a bulk operation, finished
+	WalkDuration(d time.Duration)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ACLRead(int)                {}
+func (nopMetrics) ACLWritten(int)             {}
+func (nopMetrics) Error(string)               {}
+func (nopMetrics) WalkDuration(time.Duration) {}
+
+var (
+	metricsMu      sync.RWMutex
+	packageMetrics Metrics = nopMetrics{}
+)
+
+//Sets where measurements go. nil turns them off, which is the default
+func SetMetrics(metrics Metrics) {
+	if metrics == nil {
+		metrics = nopMetrics{}
+	}
+	metricsMu.Lock()
+	packageMetrics = metrics
+	metricsMu.Unlock()
+}
+
+func currentMetrics() Metrics {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	return packageMetrics
+}
+
+//Sorts err into one of the ERROR_CLASS values
+func ErrorClass(err error) string {
+	switch {
+	case IsNotSupported(err):
+		return ERROR_CLASS_NOT_SUPPORTED
+	case IsNoACL(err):
+		return ERROR_CLASS_NO_ACL
+	case IsPermission(err):
+		return ERROR_CLASS_PERMISSION
+	case IsStale(err):
+		return ERROR_CLASS_STALE
+	case errors.Is(err, ErrXattrTooLarge) || errors.Is(err, ErrTooManyACEs):
+		return ERROR_CLASS_TOO_LARGE
+	}
+	return ERROR_CLASS_OTHER
+}
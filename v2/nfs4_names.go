@@ -0,0 +1,149 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+//Names of the access mask bits, as RFC 7530 gives them without the ACE4_
+//prefix, for files
+var accessMaskNames = map[AccessMask]string{
+	NFS4_ACE_READ_DATA:         "READ_DATA",
+	NFS4_ACE_WRITE_DATA:        "WRITE_DATA",
+	NFS4_ACE_APPEND_DATA:       "APPEND_DATA",
+	NFS4_ACE_READ_NAMED_ATTRS:  "READ_NAMED_ATTRS",
+	NFS4_ACE_WRITE_NAMED_ATTRS: "WRITE_NAMED_ATTRS",
+	NFS4_ACE_EXECUTE:           "EXECUTE",
+	NFS4_ACE_DELETE_CHILD:      "DELETE_CHILD",
+	NFS4_ACE_READ_ATTRIBUTES:   "READ_ATTRIBUTES",
+	NFS4_ACE_WRITE_ATTRIBUTES:  "WRITE_ATTRIBUTES",
+	NFS4_ACE_DELETE:            "DELETE",
+	NFS4_ACE_READ_ACL:          "READ_ACL",
+	NFS4_ACE_WRITE_ACL:         "WRITE_ACL",
+	NFS4_ACE_WRITE_OWNER:       "WRITE_OWNER",
+	NFS4_ACE_SYNCHRONIZE:       "SYNCHRONIZE",
+}
+
+//Same as accessMaskNames, with the names the data bits go by on directories,
+//e.g. LIST_DIRECTORY instead of READ_DATA
+var dirAccessMaskNames = map[AccessMask]string{
+	NFS4_ACE_LIST_DIRECTORY:    "LIST_DIRECTORY",
+	NFS4_ACE_ADD_FILE:          "ADD_FILE",
+	NFS4_ACE_ADD_SUBDIRECTORY:  "ADD_SUBDIRECTORY",
+	NFS4_ACE_READ_NAMED_ATTRS:  "READ_NAMED_ATTRS",
+	NFS4_ACE_WRITE_NAMED_ATTRS: "WRITE_NAMED_ATTRS",
+	NFS4_ACE_EXECUTE:           "EXECUTE",
+	NFS4_ACE_DELETE_CHILD:      "DELETE_CHILD",
+	NFS4_ACE_READ_ATTRIBUTES:   "READ_ATTRIBUTES",
+	NFS4_ACE_WRITE_ATTRIBUTES:  "WRITE_ATTRIBUTES",
+	NFS4_ACE_DELETE:            "DELETE",
+	NFS4_ACE_READ_ACL:          "READ_ACL",
+	NFS4_ACE_WRITE_ACL:         "WRITE_ACL",
+	NFS4_ACE_WRITE_OWNER:       "WRITE_OWNER",
+	NFS4_ACE_SYNCHRONIZE:       "SYNCHRONIZE",
+}
+
+//Access mask bits by name, file and directory names alike
+var accessMaskByName = reverseAccessMaskNames()
+
+//Names of the flag bits that go over the wire, as RFC 7530 gives them
+//without the ACE4_ prefix and _ACE or _ACE_FLAG suffix
+var flagNames = map[AceFlags]string{
+	NFS4_ACE_FILE_INHERIT_ACE:           "FILE_INHERIT",
+	NFS4_ACE_DIRECTORY_INHERIT_ACE:      "DIRECTORY_INHERIT",
+	NFS4_ACE_NO_PROPAGATE_INHERIT_ACE:   "NO_PROPAGATE_INHERIT",
+	NFS4_ACE_INHERIT_ONLY_ACE:           "INHERIT_ONLY",
+	NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG: "SUCCESSFUL_ACCESS",
+	NFS4_ACE_FAILED_ACCESS_ACE_FLAG:     "FAILED_ACCESS",
+	NFS4_ACE_IDENTIFIER_GROUP:           "IDENTIFIER_GROUP",
+	NFS4_ACE_INHERITED_ACE:              "INHERITED",
+}
+
+//Flag bits by name
+var flagByName = reverseFlagNames()
+
+//Order PermNames lists the bits in, that of the letters of PermsString
+var permNameOrder = []AccessMask{
+	NFS4_ACE_READ_DATA,
+	NFS4_ACE_WRITE_DATA,
+	NFS4_ACE_APPEND_DATA,
+	NFS4_ACE_DELETE_CHILD,
+	NFS4_ACE_DELETE,
+	NFS4_ACE_EXECUTE,
+	NFS4_ACE_READ_ATTRIBUTES,
+	NFS4_ACE_WRITE_ATTRIBUTES,
+	NFS4_ACE_READ_NAMED_ATTRS,
+	NFS4_ACE_WRITE_NAMED_ATTRS,
+	NFS4_ACE_READ_ACL,
+	NFS4_ACE_WRITE_ACL,
+	NFS4_ACE_WRITE_OWNER,
+	NFS4_ACE_SYNCHRONIZE,
+}
+
+//Returns the name of the single access mask bit, "" for an unknown bit or
+//several. isDir selects the directory names
+func AccessMaskName(bit AccessMask, isDir bool) string {
+	if isDir {
+		return dirAccessMaskNames[bit]
+	}
+	return accessMaskNames[bit]
+}
+
+//Returns the names of all access mask bits, for files or, with isDir, for
+//directories. The map is a copy the caller may change
+func AccessMaskNames(isDir bool) map[AccessMask]string {
+	table := accessMaskNames
+	if isDir {
+		table = dirAccessMaskNames
+	}
+	names := make(map[AccessMask]string, len(table))
+	for bit, name := range table {
+		names[bit] = name
+	}
+	return names
+}
+
+//Returns the access mask bit named name, file and directory names alike, and
+//whether there is one
+func AccessMaskByName(name string) (AccessMask, bool) {
+	bit, ok := accessMaskByName[name]
+	return bit, ok
+}
+
+//Returns the name of the single flag bit, "" for an unknown bit or several
+func FlagName(bit AceFlags) string {
+	return flagNames[bit]
+}
+
+//Returns the names of all flag bits that go over the wire. The map is a copy
+//the caller may change
+func FlagNames() map[AceFlags]string {
+	names := make(map[AceFlags]string, len(flagNames))
+	for bit, name := range flagNames {
+		names[bit] = name
+	}
+	return names
+}
+
+//Returns the flag bit named name and whether there is one
+func FlagByName(name string) (AceFlags, bool) {
+	bit, ok := flagByName[name]
+	return bit, ok
+}
+
+func reverseAccessMaskNames() map[string]AccessMask {
+	byName := make(map[string]AccessMask)
+	for _, table := range []map[AccessMask]string{accessMaskNames, dirAccessMaskNames} {
+		for bit, name := range table {
+			byName[name] = bit
+		}
+	}
+	return byName
+}
+
+func reverseFlagNames() map[string]AceFlags {
+	byName := make(map[string]AceFlags, len(flagNames))
+	for bit, name := range flagNames {
+		byName[name] = bit
+	}
+	return byName
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl_test
+
+import (
+	"github.com/cclose/libnfs4acl-go/v2"
+	"testing"
+)
+
+func TestAccessMaskNames(t *testing.T) {
+	if name := nfs4acl.AccessMaskName(nfs4acl.NFS4_ACE_READ_DATA, false); name != "READ_DATA" {
+		t.Errorf("file name %q", name)
+	}
+	if name := nfs4acl.AccessMaskName(nfs4acl.NFS4_ACE_READ_DATA, true); name != "LIST_DIRECTORY" {
+		t.Errorf("directory name %q", name)
+	}
+	if name := nfs4acl.AccessMaskName(nfs4acl.NFS4_ACE_READ_DATA|nfs4acl.NFS4_ACE_WRITE_DATA, false); name != "" {
+		t.Errorf("several bits named %q", name)
+	}
+
+	for _, name := range []string{"READ_DATA", "LIST_DIRECTORY"} {
+		if bit, ok := nfs4acl.AccessMaskByName(name); !ok || bit != nfs4acl.NFS4_ACE_READ_DATA {
+			t.Errorf("%s is %#x, %t", name, uint32(bit), ok)
+		}
+	}
+	if _, ok := nfs4acl.AccessMaskByName("NO_SUCH_BIT"); ok {
+		t.Error("unknown name found")
+	}
+
+	names := nfs4acl.AccessMaskNames(false)
+	names[nfs4acl.NFS4_ACE_READ_DATA] = "changed"
+	if name := nfs4acl.AccessMaskName(nfs4acl.NFS4_ACE_READ_DATA, false); name != "READ_DATA" {
+		t.Errorf("changing the returned map renamed the bit to %q", name)
+	}
+}
+
+func TestFlagNames(t *testing.T) {
+	if name := nfs4acl.FlagName(nfs4acl.NFS4_ACE_FILE_INHERIT_ACE); name != "FILE_INHERIT" {
+		t.Errorf("name %q", name)
+	}
+	if bit, ok := nfs4acl.FlagByName("FILE_INHERIT"); !ok || bit != nfs4acl.NFS4_ACE_FILE_INHERIT_ACE {
+		t.Errorf("FILE_INHERIT is %#x, %t", uint32(bit), ok)
+	}
+
+	names := nfs4acl.FlagNames()
+	delete(names, nfs4acl.NFS4_ACE_FILE_INHERIT_ACE)
+	if _, ok := nfs4acl.FlagByName("FILE_INHERIT"); !ok {
+		t.Error("changing the returned map dropped the flag")
+	}
+	if len(nfs4acl.FlagNames()) != len(names)+1 {
+		t.Error("changing the returned map changed the table")
+	}
+}
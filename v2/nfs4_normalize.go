@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"strings"
+	"sync"
+)
+
+//WhoNormalization selects how whos are rewritten when ACEs are parsed from a
+//spec or JSON and when ACLs are loaded from an xattr, so principals the server
+//treats as the same compare and dedup as equal. The zero value keeps whos
+//exactly as given. Normalizing on load means a loaded ACL may no longer
+//pack to the bytes the server returned
+type WhoNormalization struct {
+	TrimSpace   bool //strip surrounding whitespace
+	LowerName   bool //lowercase the name before the last '@'
+	LowerDomain bool //lowercase the NFSv4 domain after the last '@'
+	SpecialCase bool //uppercase special identifiers given as e.g. "owner@"
+}
+
+var specialWhos = []string{
+	NFS4_ACL_WHO_OWNER_STRING,
+	NFS4_ACL_WHO_GROUP_STRING,
+	NFS4_ACL_WHO_EVERYONE_STRING,
+	NFS4_ACL_WHO_ANONYMOUS_STRING,
+	NFS4_ACL_WHO_AUTHENTICATED_STRING,
+	NFS4_ACL_WHO_INTERACTIVE_STRING,
+	NFS4_ACL_WHO_NETWORK_STRING,
+	NFS4_ACL_WHO_DIALUP_STRING,
+	NFS4_ACL_WHO_BATCH_STRING,
+	NFS4_ACL_WHO_SERVICE_STRING,
+}
+
+var (
+	normalizationMu  sync.RWMutex
+	whoNormalization WhoNormalization
+)
+
+//Sets the normalization applied to every who parsed or loaded from then on
+func SetWhoNormalization(n WhoNormalization) {
+	normalizationMu.Lock()
+	whoNormalization = n
+	normalizationMu.Unlock()
+}
+
+//Returns the normalization set by SetWhoNormalization
+func GetWhoNormalization() WhoNormalization {
+	normalizationMu.RLock()
+	defer normalizationMu.RUnlock()
+	return whoNormalization
+}
+
+//Rewrites who according to n
+func (n WhoNormalization) Normalize(who string) string {
+	if n == (WhoNormalization{}) {
+		return who
+	}
+	if n.TrimSpace {
+		who = strings.TrimSpace(who)
+	}
+	if n.SpecialCase {
+		for _, special := range specialWhos {
+			if strings.EqualFold(who, special) {
+				return special
+			}
+		}
+	}
+
+	at := strings.LastIndex(who, "@")
+	if at < 0 {
+		at = len(who)
+	}
+	name, domain := who[:at], who[at:]
+	if n.LowerName && AceGetWhoType(who) == NFS4_ACL_WHO_NAMED {
+		name = strings.ToLower(name)
+	}
+	if n.LowerDomain {
+		domain = strings.ToLower(domain)
+	}
+	return name + domain
+}
+
+//Applies the normalization set by SetWhoNormalization
+func normalizeWho(who string) string {
+	return GetWhoNormalization().Normalize(who)
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"io/fs"
+	"time"
+)
+
+//Number of concurrent workers used by bulk operations unless overridden.
+//NFS round trips dominate, so this is deliberately higher than the CPU count
+const DEFAULT_WORKERS = 8
+
+//Times LoadACL refetches an ACL that grew between sizing and reading it
+const DEFAULT_RANGE_RETRIES = 3
+
+//Option configures library operations such as ApplyRecursive
+type Option func(*options)
+
+type options struct {
+	workers  int
+	walk     WalkOptions
+	onError  func(path string, err error) error
+	onResult ResultFunc
+
+	progress         ProgressFunc
+	progressInterval time.Duration
+
+	exclusive bool
+
+	logger Logger
+
+	rangeRetries    int
+	xattrSlack      int
+	conflictRetries int
+	retry           RetryPolicy
+	expect          fs.FileInfo
+
+	zfs bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		workers:      DEFAULT_WORKERS,
+		rangeRetries: DEFAULT_RANGE_RETRIES,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+
+	return o
+}
+
+//Sets the number of paths processed concurrently
+func WithWorkers(workers int) Option {
+	return func(o *options) {
+		o.workers = workers
+	}
+}
+
+//Sets how the tree is traversed
+func WithWalkOptions(walk WalkOptions) Option {
+	return func(o *options) {
+		o.walk = walk
+	}
+}
+
+//Sets a handler invoked for every per-path failure. Returning nil records the
+//failure as handled and continues, returning an error aborts the operation.
+//Without a handler the first failure aborts
+func WithErrorHandler(handler func(path string, err error) error) Option {
+	return func(o *options) {
+		o.onError = handler
+	}
+}
+
+//ResultFunc receives every path a bulk operation processed successfully, with
+//a copy of its ACL as it now stands and whether it was written. It may be
+//called concurrently from several workers
+type ResultFunc func(path string, acl *ACL, changed bool)
+
+//Sets a handler invoked for every path processed without error, for
+//per-path reporting such as streaming logs
+func WithResultHandler(handler ResultFunc) Option {
+	return func(o *options) {
+		o.onResult = handler
+	}
+}
+
+//Sets how many times LoadACL refetches an ACL that grew between sizing and
+//reading it (ERANGE) before giving up
+func WithRangeRetries(retries int) Option {
+	return func(o *options) {
+		o.rangeRetries = retries
+	}
+}
+
+//Makes LoadACL allocate bytes more than the probed size, so an ACL growing
+//by a few ACEs meanwhile still fits without a retry
+func WithXattrSlack(bytes int) Option {
+	return func(o *options) {
+		o.xattrSlack = max(bytes, 0)
+	}
+}
+
+//Sets how many times ModifyACL starts over when the ACL changed while fn was
+//working on it. Without retries the change fails with ErrConflict
+func WithConflictRetries(retries int) Option {
+	return func(o *options) {
+		o.conflictRetries = max(retries, 0)
+	}
+}
+
+//Makes the inheritance operations follow the aclinherit property of the ZFS
+//dataset holding each directory, as ZFSProperties.InheritedACL does, instead
+//of plain RFC 5661 inheritance. Properties come from ZFSPropertiesForPath
+func WithZFS() Option {
+	return func(o *options) {
+		o.zfs = true
+	}
+}
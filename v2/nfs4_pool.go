@@ -0,0 +1,47 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"sync"
+)
+
+//Size of the buffers LoadACL starts with. Most ACLs fit, so the xattr is
+//usually read without probing its size first
+const DEFAULT_XATTR_BUFFER_SIZE = 4096
+
+//Buffers larger than this are left to the garbage collector rather than
+//kept in the pool
+const MAX_POOLED_XATTR_BUFFER = DEFAULT_MAX_XATTR_SIZE
+
+//Buffers for raw xattr values, reused across reads and writes so bulk scans
+//don't allocate one per path. The values are always copied out before a
+//buffer is returned
+var xattrBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, DEFAULT_XATTR_BUFFER_SIZE)
+		return &buf
+	},
+}
+
+func getXattrBuffer() *[]byte {
+	return xattrBuffers.Get().(*[]byte)
+}
+
+func putXattrBuffer(buf *[]byte) {
+	if cap(*buf) > MAX_POOLED_XATTR_BUFFER {
+		return
+	}
+	*buf = (*buf)[:cap(*buf)]
+	xattrBuffers.Put(buf)
+}
+
+//Resizes buf to size bytes, reallocating only when it is too small
+func growBuffer(buf []byte, size int) []byte {
+	if cap(buf) < size {
+		return make([]byte, size)
+	}
+	return buf[:size]
+}
@@ -0,0 +1,300 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"text/template"
+)
+
+//PrintOptions controls how FormatACL and PrintACLWith render an ACL. The zero
+//value gives the same output as PrintACL(false)
+type PrintOptions struct {
+	//Render ace types as words, as PrintACL(true) does
+	Verbose bool
+	//List permissions by name, one per line below their ACE, instead of as
+	//letters
+	LongPerms bool
+
+	//Follow OWNER@ and GROUP@ with the owning user and group of the file, e.g.
+	//"A::OWNER@(bob):rwatTnNcCy". Needs FileInfo. The result is for reading
+	//only, it can't be parsed back
+	ResolveOwner bool
+	//Stat of the file the ACL belongs to
+	FileInfo fs.FileInfo
+
+	//Pad the type, flags and who columns so the fields of all ACEs line up.
+	//Like ResolveOwner, the result can't be parsed back
+	Align bool
+	//List the ACEs per who, see ACL.ByWho: each who on a line of its own,
+	//followed by its ACEs indented and without the who field. Like Align,
+	//the result can't be parsed back
+	Grouped bool
+	//Color the output with ANSI escapes: types by kind, whos, the flags of
+	//inherited entries, and deny entries as a whole. Callers should only set
+	//it for terminals
+	Color bool
+}
+
+//ANSI escapes used when PrintOptions.Color is set
+const (
+	colorReset     = "\x1b[0m"
+	colorAllow     = "\x1b[32m"   //green
+	colorDeny      = "\x1b[1;31m" //bold red
+	colorAudit     = "\x1b[33m"   //yellow
+	colorWho       = "\x1b[36m"   //cyan
+	colorInherited = "\x1b[2m"    //dim
+)
+
+//Renders the ACL one ACE per line according to opts
+func (acl *ACL) FormatACL(opts PrintOptions) string {
+	rows := make([]aceFields, len(acl.aceList))
+	var widths [4]int
+	for i := range acl.aceList {
+		rows[i] = acl.aceList[i].fields(opts, acl.isDirectory)
+		if opts.Align {
+			for col := range rows[i].cols {
+				widths[col] = max(widths[col], len(rows[i].cols[col]))
+			}
+		}
+	}
+
+	if opts.Grouped {
+		return acl.formatGrouped(rows, opts, widths)
+	}
+
+	var buffer strings.Builder
+	for i := range acl.aceList {
+		buffer.WriteString(rows[i].render(&acl.aceList[i], opts, widths))
+		buffer.WriteRune('\n')
+	}
+	return buffer.String()
+}
+
+//Renders the rows of FormatACL under a heading per who, the whos in order of
+//first appearance
+func (acl *ACL) formatGrouped(rows []aceFields, opts PrintOptions, widths [4]int) string {
+	groups := make(map[string][]int)
+	for i, ace := range acl.aceList {
+		groups[ace.Who] = append(groups[ace.Who], i)
+	}
+
+	var buffer strings.Builder
+	for _, who := range acl.Whos() {
+		indexes := groups[who]
+		//the rendered who, with the owner if resolved
+		heading := rows[indexes[0]].cols[2]
+		if opts.Color {
+			heading = colorWho + heading + colorReset
+		}
+		buffer.WriteString(heading)
+		buffer.WriteRune('\n')
+
+		for _, i := range indexes {
+			row := rows[i].render(&acl.aceList[i], opts, widths)
+			buffer.WriteRune('\t')
+			//permission names go one level deeper
+			buffer.WriteString(strings.ReplaceAll(row, "\n", "\n\t"))
+			buffer.WriteRune('\n')
+		}
+	}
+	return buffer.String()
+}
+
+//Prints the ACL according to opts, see FormatACL
+func (acl *ACL) PrintACLWith(opts PrintOptions) error {
+	_, err := fmt.Print(acl.FormatACL(opts))
+	return err
+}
+
+//Renders the Ace according to opts, see Format. A single Ace has nothing to
+//align with or group, so Align and Grouped are ignored
+func (ace *ACE) FormatWith(opts PrintOptions, isDir bool) string {
+	opts.Grouped = false
+	return ace.fields(opts, isDir).render(ace, opts, [4]int{})
+}
+
+//The rendered type, flags, who and perms of an Ace
+type aceFields struct {
+	cols      [4]string
+	permNames []string //only with LongPerms
+}
+
+func (ace *ACE) fields(opts PrintOptions, isDir bool) aceFields {
+	who := ace.Who
+	if opts.ResolveOwner && opts.FileInfo != nil {
+		if owner := ownerDisplayName(ace.WhoType, opts.FileInfo); owner != "" {
+			who = fmt.Sprintf("%s(%s)", ace.Who, owner)
+		}
+	}
+
+	f := aceFields{cols: [4]string{
+		AceTypeString(ace.AceType, opts.Verbose),
+		FlagsString(ace.Flags),
+		who,
+	}}
+	if opts.LongPerms {
+		//the letters are replaced by the name list
+		f.permNames = PermNames(ace.AccessMask, isDir)
+	} else {
+		f.cols[3] = PermsString(ace.AccessMask, isDir)
+	}
+	return f
+}
+
+//Joins the fields, padding each column to widths when aligning
+func (f aceFields) render(ace *ACE, opts PrintOptions, widths [4]int) string {
+	//deny entries are highlighted as a whole, otherwise only the type and
+	//who are colored
+	highlight := opts.Color && ace.AceType == NFS4_ACE_ACCESS_DENIED_ACE_TYPE
+	colorFields := opts.Color && !highlight
+
+	var buffer strings.Builder
+	if highlight {
+		buffer.WriteString(colorDeny)
+	}
+	for col, text := range f.cols {
+		//the heading of the group names the who
+		if opts.Grouped && col == 2 {
+			continue
+		}
+		if col > 0 {
+			buffer.WriteRune(':')
+		}
+
+		color := ""
+		if colorFields {
+			color = fieldColor(ace, col)
+		}
+		if color != "" {
+			buffer.WriteString(color)
+		}
+		buffer.WriteString(text)
+		if color != "" {
+			buffer.WriteString(colorReset)
+		}
+
+		//the last column needs no padding
+		if col < len(f.cols)-1 {
+			buffer.WriteString(strings.Repeat(" ", max(widths[col]-len(text), 0)))
+		}
+	}
+
+	for _, name := range f.permNames {
+		buffer.WriteString("\n\t")
+		buffer.WriteString(name)
+	}
+	if highlight {
+		buffer.WriteString(colorReset)
+	}
+	return buffer.String()
+}
+
+func fieldColor(ace *ACE, col int) string {
+	switch col {
+	case 0:
+		if ace.AceType == NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE {
+			return colorAllow
+		}
+		return colorAudit
+	case 1:
+		if ace.IsInherited() {
+			return colorInherited
+		}
+	case 2:
+		return colorWho
+	}
+	return ""
+}
+
+//Fields of an ACE as seen by FormatTemplate
+type TemplateACE struct {
+	Index        int    //position in the ACL, from 1
+	Type         string //letter, e.g. "A"
+	TypeVerbose  string //word, e.g. "ALLOW"
+	Flags        string //letters, e.g. "fd"
+	Who          string
+	Perms        string //letters, e.g. "rwx"
+	PermsVerbose string //names separated by commas, e.g. "READ_DATA,EXECUTE"
+	Mask         uint32 //raw bits, for {{printf "%#x" .Mask}}
+	Inherited    bool
+	IsDir        bool //whether the ACL belongs to a directory
+}
+
+//Renders the ACL by executing the text/template tmpl on the TemplateACE of
+//each ACE, following each with a newline. "{{.Who}}\t{{.PermsVerbose}}"
+//lists who is granted what, for example
+func (acl *ACL) FormatTemplate(tmpl string) (string, error) {
+	t, err := template.New("ace").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer strings.Builder
+	for i, ace := range acl.aceList {
+		data := TemplateACE{
+			Index:        i + 1,
+			Type:         AceTypeString(ace.AceType, false),
+			TypeVerbose:  AceTypeString(ace.AceType, true),
+			Flags:        FlagsString(ace.Flags),
+			Who:          ace.Who,
+			Perms:        PermsString(ace.AccessMask, acl.isDirectory),
+			PermsVerbose: strings.Join(PermNames(ace.AccessMask, acl.isDirectory), ","),
+			Mask:         uint32(ace.AccessMask),
+			Inherited:    ace.IsInherited(),
+			IsDir:        acl.isDirectory,
+		}
+		if err := t.Execute(&buffer, data); err != nil {
+			return "", err
+		}
+		buffer.WriteRune('\n')
+	}
+	return buffer.String(), nil
+}
+
+//Returns the names of the permissions in mask, in the order PermsString
+//prints their letters. isDir selects the directory names, e.g. LIST_DIRECTORY
+//instead of READ_DATA
+func PermNames(mask AccessMask, isDir bool) []string {
+	table := accessMaskNames
+	if isDir {
+		table = dirAccessMaskNames
+	}
+
+	var names []string
+	for _, bit := range permNameOrder {
+		//DELETE_CHILD means nothing on files
+		if mask&bit != 0 && (isDir || bit != NFS4_ACE_DELETE_CHILD) {
+			names = append(names, table[bit])
+		}
+	}
+	return names
+}
+
+//Name of the user or group that OWNER@ or GROUP@ stands for on the file, or
+//"" for other whos. Unresolvable ids are given as numbers
+func ownerDisplayName(whoType uint, info fs.FileInfo) string {
+	uid, gid, ok := OwnerIDs(info)
+	if !ok {
+		return ""
+	}
+
+	switch whoType {
+	case NFS4_ACL_WHO_OWNER:
+		if name, err := GetPrincipalResolver().UserName(uid); err == nil {
+			return name
+		}
+		return fmt.Sprint(uid)
+	case NFS4_ACL_WHO_GROUP:
+		if name, err := GetPrincipalResolver().GroupName(gid); err == nil {
+			return name
+		}
+		return fmt.Sprint(gid)
+	}
+
+	return ""
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"fmt"
+	"os"
+)
+
+//Every access mask bit with a permission letter
+const probeAccessMask = NFS4_ACE_READ_DATA | NFS4_ACE_WRITE_DATA | NFS4_ACE_APPEND_DATA |
+	NFS4_ACE_READ_NAMED_ATTRS | NFS4_ACE_WRITE_NAMED_ATTRS | NFS4_ACE_EXECUTE |
+	NFS4_ACE_DELETE_CHILD | NFS4_ACE_READ_ATTRIBUTES | NFS4_ACE_WRITE_ATTRIBUTES |
+	NFS4_ACE_DELETE | NFS4_ACE_READ_ACL | NFS4_ACE_WRITE_ACL | NFS4_ACE_WRITE_OWNER |
+	NFS4_ACE_SYNCHRONIZE
+
+//Capabilities is what a server was seen to keep when ACEs were written to it,
+//see ProbeCapabilities
+type Capabilities struct {
+	AccessMask AccessMask //mask bits that survive a round trip
+	Flags      AceFlags   //ace flags that survive a round trip
+	AceTypes   []AceType  //ace types the server accepts
+}
+
+//Discovers which mask bits, flags and ace types the server holding dir
+//really stores, by writing ACEs to a scratch directory created in dir,
+//reading them back and comparing. The scratch directory is removed again.
+//Servers such as the Linux knfsd map ACLs onto POSIX ACLs and drop or merge
+//what doesn't fit; the result is a best effort view of that behaviour
+func ProbeCapabilities(dir string) (*Capabilities, error) {
+	scratch, err := os.MkdirTemp(dir, ".nfs4acl-probe-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(scratch)
+
+	base, err := LoadACL(scratch, true)
+	if err != nil {
+		return nil, err
+	}
+	caps := &Capabilities{}
+
+	//mask bits, on an allow for the owner which every server supports
+	kept, err := probeRoundTrip(scratch, base, NewACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0,
+		probeAccessMask, NFS4_ACL_WHO_OWNER_STRING))
+	if err != nil {
+		return nil, err
+	}
+	if kept != nil {
+		caps.AccessMask = kept.AccessMask & probeAccessMask
+	}
+
+	for _, aceType := range []AceType{NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, NFS4_ACE_ACCESS_DENIED_ACE_TYPE,
+		NFS4_ACE_SYSTEM_AUDIT_ACE_TYPE, NFS4_ACE_SYSTEM_ALARM_ACE_TYPE} {
+		kept, err := probeRoundTrip(scratch, base, NewACE(aceType, 0,
+			NFS4_ACE_READ_DATA, NFS4_ACL_WHO_EVERYONE_STRING))
+		if err == nil && kept != nil {
+			caps.AceTypes = append(caps.AceTypes, aceType)
+		}
+	}
+
+	//each flag alone, with what it needs to be valid
+	flagProbes := []struct {
+		flag AceFlags
+		ace  *ACE
+	}{
+		{NFS4_ACE_FILE_INHERIT_ACE, NewACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE,
+			NFS4_ACE_FILE_INHERIT_ACE, NFS4_ACE_READ_DATA, NFS4_ACL_WHO_EVERYONE_STRING)},
+		{NFS4_ACE_DIRECTORY_INHERIT_ACE, NewACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE,
+			NFS4_ACE_DIRECTORY_INHERIT_ACE, NFS4_ACE_READ_DATA, NFS4_ACL_WHO_EVERYONE_STRING)},
+		{NFS4_ACE_NO_PROPAGATE_INHERIT_ACE, NewACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE,
+			NFS4_ACE_FILE_INHERIT_ACE|NFS4_ACE_NO_PROPAGATE_INHERIT_ACE, NFS4_ACE_READ_DATA, NFS4_ACL_WHO_EVERYONE_STRING)},
+		{NFS4_ACE_INHERIT_ONLY_ACE, NewACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE,
+			NFS4_ACE_FILE_INHERIT_ACE|NFS4_ACE_INHERIT_ONLY_ACE, NFS4_ACE_READ_DATA, NFS4_ACL_WHO_EVERYONE_STRING)},
+		{NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG, NewACE(NFS4_ACE_SYSTEM_AUDIT_ACE_TYPE,
+			NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG, NFS4_ACE_READ_DATA, NFS4_ACL_WHO_EVERYONE_STRING)},
+		{NFS4_ACE_FAILED_ACCESS_ACE_FLAG, NewACE(NFS4_ACE_SYSTEM_AUDIT_ACE_TYPE,
+			NFS4_ACE_FAILED_ACCESS_ACE_FLAG, NFS4_ACE_READ_DATA, NFS4_ACL_WHO_EVERYONE_STRING)},
+		{NFS4_ACE_IDENTIFIER_GROUP, NewACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE,
+			NFS4_ACE_IDENTIFIER_GROUP, NFS4_ACE_READ_DATA, NFS4_ACL_WHO_GROUP_STRING)},
+		{NFS4_ACE_INHERITED_ACE, NewACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE,
+			NFS4_ACE_INHERITED_ACE, NFS4_ACE_READ_DATA, NFS4_ACL_WHO_EVERYONE_STRING)},
+	}
+	for _, probe := range flagProbes {
+		kept, err := probeRoundTrip(scratch, base, probe.ace)
+		if err == nil && kept != nil {
+			caps.Flags |= kept.Flags & probe.flag
+		}
+	}
+
+	return caps, nil
+}
+
+//Writes base with ace in front to path, reads the ACL back and returns the
+//first ACE of the same type and who, or nil if the server dropped it. base is
+//written back afterwards
+func probeRoundTrip(path string, base *ACL, ace *ACE) (*ACE, error) {
+	trial := base.Clone()
+	trial.InsertACE(0, ace)
+	if err := SetACL(path, trial); err != nil {
+		return nil, err
+	}
+	defer SetACL(path, base)
+
+	readBack, err := LoadACL(path, true)
+	if err != nil {
+		return nil, err
+	}
+	kept, _ := readBack.First(NewACEMatcher().Type(ace.AceType).Who(ace.Who))
+	return kept, nil
+}
+
+//Reports what the server would drop from ace, or nil if it keeps all of it
+func (c *Capabilities) Check(ace *ACE) error {
+	typeOK := false
+	for _, aceType := range c.AceTypes {
+		typeOK = typeOK || aceType == ace.AceType
+	}
+	if !typeOK {
+		return fmt.Errorf("%s: server doesn't store %s aces", ace, AceTypeString(ace.AceType, true))
+	}
+
+	//the who pseudo flags never go over the wire
+	wireFlags := ace.Flags &^ (NFS4_ACE_OWNER | NFS4_ACE_GROUP | NFS4_ACE_EVERYONE)
+	droppedMask := ace.AccessMask &^ c.AccessMask
+	droppedFlags := wireFlags &^ c.Flags
+	if droppedMask != 0 || droppedFlags != 0 {
+		return fmt.Errorf("%s: server drops permissions %q and flags %q", ace,
+			PermsString(droppedMask, false), FlagsString(droppedFlags))
+	}
+	return nil
+}
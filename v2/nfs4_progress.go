@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//How often progress is reported when WithProgress is given no interval
+const DEFAULT_PROGRESS_INTERVAL = time.Second
+
+//Progress is a snapshot of the counters of a running bulk operation
+type Progress struct {
+	Scanned      int64 //paths whose ACL was fetched
+	Changed      int64 //ACLs written back
+	Errored      int64 //paths that failed, whether handled or not
+	BytesWritten int64 //total size of the xattrs written
+}
+
+//ProgressFunc receives periodic snapshots, plus a final one when the operation
+//returns. It is never called concurrently with itself
+type ProgressFunc func(Progress)
+
+//Reports progress of bulk operations to fn every interval
+func WithProgress(fn ProgressFunc, interval time.Duration) Option {
+	return func(o *options) {
+		o.progress = fn
+		o.progressInterval = interval
+	}
+}
+
+//Counters shared by the workers of a bulk operation
+type progressTracker struct {
+	scanned      atomic.Int64
+	changed      atomic.Int64
+	errored      atomic.Int64
+	bytesWritten atomic.Int64
+
+	fn   ProgressFunc
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+//Starts the reporting goroutine, if a ProgressFunc was configured
+func newProgressTracker(o *options) *progressTracker {
+	t := &progressTracker{
+		fn:   o.progress,
+		stop: make(chan struct{}),
+	}
+	if t.fn == nil {
+		return t
+	}
+
+	interval := o.progressInterval
+	if interval <= 0 {
+		interval = DEFAULT_PROGRESS_INTERVAL
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.fn(t.snapshot())
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+
+	return t
+}
+
+func (t *progressTracker) snapshot() Progress {
+	return Progress{
+		Scanned:      t.scanned.Load(),
+		Changed:      t.changed.Load(),
+		Errored:      t.errored.Load(),
+		BytesWritten: t.bytesWritten.Load(),
+	}
+}
+
+//Stops periodic reporting and delivers the final counts
+func (t *progressTracker) finish() {
+	if t.fn == nil {
+		return
+	}
+	close(t.stop)
+	t.wg.Wait()
+	t.fn(t.snapshot())
+}
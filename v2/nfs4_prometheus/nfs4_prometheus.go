@@ -0,0 +1,103 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4prometheus exports the measurements of libnfs4acl-go to Prometheus
+
+package nfs4prometheus
+
+import (
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+//Namespace of the metrics unless another is given to New
+const DEFAULT_NAMESPACE = "nfs4acl"
+
+//Metrics implements nfs4acl.Metrics on top of Prometheus collectors. It is a
+//prometheus.Collector itself, register it and pass it to nfs4acl.SetMetrics:
+//
+//	m := nfs4prometheus.New("")
+//	prometheus.MustRegister(m)
+//	nfs4acl.SetMetrics(m)
+type Metrics struct {
+	read         prometheus.Counter
+	written      prometheus.Counter
+	errors       *prometheus.CounterVec
+	xattrSize    *prometheus.HistogramVec
+	walkDuration prometheus.Histogram
+}
+
+//Creates the collectors, named under namespace or DEFAULT_NAMESPACE if empty
+func New(namespace string) *Metrics {
+	if namespace == "" {
+		namespace = DEFAULT_NAMESPACE
+	}
+
+	return &Metrics{
+		read: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "acls_read_total",
+			Help:      "ACLs read.",
+		}),
+		written: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "acls_written_total",
+			Help:      "ACLs written.",
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Failed ACL reads and writes, by class of error.",
+		}, []string{"class"}),
+		xattrSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "xattr_size_bytes",
+			Help:      "Size of the ACL xattrs read and written.",
+			Buckets:   prometheus.ExponentialBuckets(32, 4, 7), //32 bytes to 128KiB
+		}, []string{"op"}),
+		walkDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "walk_duration_seconds",
+			Help:      "Time taken by tree walks.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 10, 7), //10ms to 2.7h
+		}),
+	}
+}
+
+func (m *Metrics) ACLRead(size int) {
+	m.read.Inc()
+	m.xattrSize.WithLabelValues("read").Observe(float64(size))
+}
+
+func (m *Metrics) ACLWritten(size int) {
+	m.written.Inc()
+	m.xattrSize.WithLabelValues("write").Observe(float64(size))
+}
+
+func (m *Metrics) Error(class string) {
+	m.errors.WithLabelValues(class).Inc()
+}
+
+func (m *Metrics) WalkDuration(d time.Duration) {
+	m.walkDuration.Observe(d.Seconds())
+}
+
+//Implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.read.Describe(ch)
+	m.written.Describe(ch)
+	m.errors.Describe(ch)
+	m.xattrSize.Describe(ch)
+	m.walkDuration.Describe(ch)
+}
+
+//Implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.read.Collect(ch)
+	m.written.Collect(ch)
+	m.errors.Collect(ch)
+	m.xattrSize.Collect(ch)
+	m.walkDuration.Collect(ch)
+}
+
+var _ nfs4acl.Metrics = (*Metrics)(nil)
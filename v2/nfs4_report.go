@@ -0,0 +1,203 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"sync"
+)
+
+//Kinds of Finding
+const (
+	FINDING_EVERYONE_WRITE = "everyone_write" //EVERYONE@ may write or append
+	FINDING_ORPHANED_WHO   = "orphaned_who"   //numeric who matching no user or group
+	FINDING_DENY_HEAVY     = "deny_heavy"     //more deny than allow ACEs
+	FINDING_ERROR          = "error"          //the ACL could not be read
+)
+
+//Finding is a single notable property of the ACL of a path
+type Finding struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+//Report aggregates findings over many ACLs, typically a whole tree scanned by
+//ScanReport. Add may be called concurrently
+type Report struct {
+	Scanned    int            `json:"scanned"`
+	Trivial    int            `json:"trivial"` //see IsTrivial
+	NonTrivial int            `json:"non_trivial"`
+	Counts     map[string]int `json:"counts"` //findings per kind
+	Findings   []Finding      `json:"findings"`
+
+	mu sync.Mutex
+}
+
+//Creates an empty report, to be filled with Add
+func NewReport() *Report {
+	return &Report{
+		Counts:   make(map[string]int),
+		Findings: []Finding{},
+	}
+}
+
+//Scans every ACL of the tree rooted at root. Paths whose ACL can't be read
+//are reported as FINDING_ERROR rather than stopping the scan
+func ScanReport(root string, opts WalkOptions) (*Report, error) {
+	return ScanReportContext(context.Background(), root, opts)
+}
+
+//Same as ScanReport, but stops as soon as ctx is done and returns ctx.Err()
+func ScanReportContext(ctx context.Context, root string, opts WalkOptions) (*Report, error) {
+	report := NewReport()
+	err := WalkACLsContext(ctx, root, opts, func(path string, info fs.FileInfo, acl *ACL, err error) error {
+		if err != nil {
+			report.AddError(path, err)
+		} else {
+			report.Add(path, acl)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+//Examines the ACL of path and records what it finds
+func (r *Report) Add(path string, acl *ACL) {
+	findings := examineACL(path, acl)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Scanned++
+	if acl.IsTrivial() {
+		r.Trivial++
+	} else {
+		r.NonTrivial++
+	}
+	for _, finding := range findings {
+		r.record(finding)
+	}
+}
+
+//Records that the ACL of path could not be read
+func (r *Report) AddError(path string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record(Finding{Path: path, Kind: FINDING_ERROR, Detail: err.Error()})
+}
+
+func (r *Report) record(finding Finding) {
+	r.Counts[finding.Kind]++
+	r.Findings = append(r.Findings, finding)
+}
+
+//Lists the findings of acl, read from path
+func examineACL(path string, acl *ACL) []Finding {
+	var findings []Finding
+	allows, denies := 0, 0
+	for _, ace := range acl.aceList {
+		switch ace.AceType {
+		case NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE:
+			allows++
+			if ace.WhoType == NFS4_ACL_WHO_EVERYONE && ace.Flags&NFS4_ACE_INHERIT_ONLY_ACE == 0 &&
+				ace.AccessMask&(NFS4_ACE_WRITE_DATA|NFS4_ACE_APPEND_DATA) != 0 {
+				findings = append(findings, Finding{Path: path, Kind: FINDING_EVERYONE_WRITE,
+					Detail: PermsString(ace.AccessMask, acl.isDirectory)})
+			}
+		case NFS4_ACE_ACCESS_DENIED_ACE_TYPE:
+			denies++
+		}
+
+		if isOrphanedWho(&ace) {
+			findings = append(findings, Finding{Path: path, Kind: FINDING_ORPHANED_WHO, Detail: ace.Who})
+		}
+	}
+	if denies > allows {
+		findings = append(findings, Finding{Path: path, Kind: FINDING_DENY_HEAVY,
+			Detail: fmt.Sprintf("%d deny, %d allow", denies, allows)})
+	}
+
+	return findings
+}
+
+//Reports whether ace names a numeric uid or gid the resolver doesn't know
+func isOrphanedWho(ace *ACE) bool {
+	id := bareWho(ace.Who)
+	if !IsNumericWho(id) {
+		return false
+	}
+	n, err := parseID(id)
+	if err != nil {
+		return false
+	}
+
+	if ace.Flags&NFS4_ACE_IDENTIFIER_GROUP != 0 {
+		_, err = GetPrincipalResolver().GroupName(n)
+	} else {
+		_, err = GetPrincipalResolver().UserName(n)
+	}
+	return isUnknownPrincipal(err)
+}
+
+//Reports whether the ACL says no more than the mode bits could: allow and
+//deny ACEs for OWNER@, GROUP@ and EVERYONE@ only, without inheritance
+func (acl *ACL) IsTrivial() bool {
+	for _, ace := range acl.aceList {
+		if ace.AceType != NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE && ace.AceType != NFS4_ACE_ACCESS_DENIED_ACE_TYPE {
+			return false
+		}
+		switch ace.WhoType {
+		case NFS4_ACL_WHO_OWNER, NFS4_ACL_WHO_GROUP, NFS4_ACL_WHO_EVERYONE:
+		default:
+			return false
+		}
+		if ace.Flags&^NFS4_ACE_IDENTIFIER_GROUP != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+//Writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+//Writes the findings as CSV with a path,kind,detail header, followed by the
+//totals as rows with an empty path
+func (r *Report) WriteCSV(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := csv.NewWriter(w)
+	out.Write([]string{"path", "kind", "detail"})
+	for _, finding := range r.Findings {
+		out.Write([]string{finding.Path, finding.Kind, finding.Detail})
+	}
+
+	out.Write([]string{"", "total_scanned", strconv.Itoa(r.Scanned)})
+	out.Write([]string{"", "total_trivial", strconv.Itoa(r.Trivial)})
+	out.Write([]string{"", "total_non_trivial", strconv.Itoa(r.NonTrivial)})
+	for _, kind := range []string{FINDING_EVERYONE_WRITE, FINDING_ORPHANED_WHO, FINDING_DENY_HEAVY, FINDING_ERROR} {
+		out.Write([]string{"", "total_" + kind, strconv.Itoa(r.Counts[kind])})
+	}
+
+	out.Flush()
+	return out.Error()
+}
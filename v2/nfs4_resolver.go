@@ -0,0 +1,215 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"container/list"
+	"errors"
+	"os/user"
+	"strconv"
+	"sync"
+)
+
+//Number of lookups remembered by the default resolver
+const DEFAULT_RESOLVER_CACHE_SIZE = 4096
+
+//PrincipalResolver translates between user and group names and their ids.
+//Names are bare local names, without the NFSv4 domain
+type PrincipalResolver interface {
+	LookupUser(name string) (uid uint32, err error)
+	LookupGroup(name string) (gid uint32, err error)
+	UserName(uid uint32) (string, error)
+	GroupName(gid uint32) (string, error)
+}
+
+var (
+	resolverMu      sync.RWMutex
+	packageResolver PrincipalResolver = NewCachingResolver(NSSResolver{}, DEFAULT_RESOLVER_CACHE_SIZE)
+)
+
+//Sets the resolver used for every name lookup made by the library. nil
+//restores the default, an NSSResolver behind a CachingResolver
+func SetPrincipalResolver(resolver PrincipalResolver) {
+	if resolver == nil {
+		resolver = NewCachingResolver(NSSResolver{}, DEFAULT_RESOLVER_CACHE_SIZE)
+	}
+	resolverMu.Lock()
+	packageResolver = resolver
+	resolverMu.Unlock()
+}
+
+//Returns the resolver used by the library, for callers that want their own
+//lookups to share its cache
+func GetPrincipalResolver() PrincipalResolver {
+	resolverMu.RLock()
+	defer resolverMu.RUnlock()
+	return packageResolver
+}
+
+//NSSResolver resolves through the system user and group databases (os/user),
+//uncached
+type NSSResolver struct{}
+
+func (NSSResolver) LookupUser(name string) (uint32, error) {
+	usr, err := user.Lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return parseID(usr.Uid)
+}
+
+func (NSSResolver) LookupGroup(name string) (uint32, error) {
+	group, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, err
+	}
+	return parseID(group.Gid)
+}
+
+func (NSSResolver) UserName(uid uint32) (string, error) {
+	usr, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return "", err
+	}
+	return usr.Username, nil
+}
+
+func (NSSResolver) GroupName(gid uint32) (string, error) {
+	group, err := user.LookupGroupId(strconv.FormatUint(uint64(gid), 10))
+	if err != nil {
+		return "", err
+	}
+	return group.Name, nil
+}
+
+func parseID(id string) (uint32, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, errors.New("non-numeric id " + strconv.Quote(id))
+	}
+	return uint32(n), nil
+}
+
+//CachingResolver remembers the most recent lookups of another resolver, so
+//large scans don't repeat an NSS or LDAP round trip for every ACE. Names and
+//ids reported as unknown are remembered too, other failures are retried
+type CachingResolver struct {
+	backend PrincipalResolver
+	size    int
+
+	mu      sync.Mutex
+	lru     *list.List //front is the most recently used
+	entries map[resolverKey]*list.Element
+}
+
+//What was looked up: one of the four PrincipalResolver methods and its argument
+type resolverKey struct {
+	op  byte
+	arg string
+}
+
+type resolverEntry struct {
+	key  resolverKey
+	id   uint32
+	name string
+	err  error
+}
+
+//Creates a resolver caching up to size lookups of backend
+func NewCachingResolver(backend PrincipalResolver, size int) *CachingResolver {
+	if size < 1 {
+		size = 1
+	}
+	return &CachingResolver{
+		backend: backend,
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[resolverKey]*list.Element),
+	}
+}
+
+func (r *CachingResolver) LookupUser(name string) (uint32, error) {
+	entry := r.lookup(resolverKey{'u', name}, func(e *resolverEntry) {
+		e.id, e.err = r.backend.LookupUser(name)
+	})
+	return entry.id, entry.err
+}
+
+func (r *CachingResolver) LookupGroup(name string) (uint32, error) {
+	entry := r.lookup(resolverKey{'g', name}, func(e *resolverEntry) {
+		e.id, e.err = r.backend.LookupGroup(name)
+	})
+	return entry.id, entry.err
+}
+
+func (r *CachingResolver) UserName(uid uint32) (string, error) {
+	entry := r.lookup(resolverKey{'U', strconv.FormatUint(uint64(uid), 10)}, func(e *resolverEntry) {
+		e.name, e.err = r.backend.UserName(uid)
+	})
+	return entry.name, entry.err
+}
+
+func (r *CachingResolver) GroupName(gid uint32) (string, error) {
+	entry := r.lookup(resolverKey{'G', strconv.FormatUint(uint64(gid), 10)}, func(e *resolverEntry) {
+		e.name, e.err = r.backend.GroupName(gid)
+	})
+	return entry.name, entry.err
+}
+
+//Drops every cached lookup
+func (r *CachingResolver) Purge() {
+	r.mu.Lock()
+	r.lru.Init()
+	r.entries = make(map[resolverKey]*list.Element)
+	r.mu.Unlock()
+}
+
+//Returns the cached entry for key, or fills a new one with resolve. The
+//backend is called without the lock held, so concurrent misses on the same
+//key may both reach it
+func (r *CachingResolver) lookup(key resolverKey, resolve func(e *resolverEntry)) resolverEntry {
+	r.mu.Lock()
+	if elem, ok := r.entries[key]; ok {
+		r.lru.MoveToFront(elem)
+		entry := *elem.Value.(*resolverEntry)
+		r.mu.Unlock()
+		return entry
+	}
+	r.mu.Unlock()
+
+	entry := &resolverEntry{key: key}
+	resolve(entry)
+	if entry.err != nil && !isUnknownPrincipal(entry.err) {
+		return *entry
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if elem, ok := r.entries[key]; ok {
+		r.lru.MoveToFront(elem)
+		return *entry
+	}
+	r.entries[key] = r.lru.PushFront(entry)
+	for r.lru.Len() > r.size {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.entries, oldest.Value.(*resolverEntry).key)
+	}
+
+	return *entry
+}
+
+//Reports whether err says the name or id doesn't exist, as opposed to the
+//lookup failing
+func isUnknownPrincipal(err error) bool {
+	var (
+		unknownUser    user.UnknownUserError
+		unknownUserID  user.UnknownUserIdError
+		unknownGroup   user.UnknownGroupError
+		unknownGroupID user.UnknownGroupIdError
+	)
+	return errors.As(err, &unknownUser) || errors.As(err, &unknownUserID) ||
+		errors.As(err, &unknownGroup) || errors.As(err, &unknownGroupID)
+}
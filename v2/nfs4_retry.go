@@ -0,0 +1,82 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"io/fs"
+	"time"
+)
+
+//Retry settings of DefaultRetryPolicy
+const (
+	DEFAULT_RETRY_ATTEMPTS    = 4
+	DEFAULT_RETRY_BACKOFF     = 100 * time.Millisecond
+	DEFAULT_RETRY_MAX_BACKOFF = 2 * time.Second
+)
+
+//RetryPolicy decides how reads and writes of an ACL retry transient errors
+//(see IsTransient). Before retrying ESTALE the path is looked up again, so the
+//client gets a fresh file handle, and a write gives up with a StaleSkipError
+//if the file is gone or was replaced. The zero value doesn't retry
+type RetryPolicy struct {
+	//Attempts in total, so 1 or less disables retrying
+	MaxAttempts int
+	//Wait before the first retry, doubled for each one after
+	Backoff time.Duration
+	//Longest wait between attempts, 0 means no cap
+	MaxBackoff time.Duration
+}
+
+//A policy suited to sweeps over NFS mounts: a few retries over about a second
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DEFAULT_RETRY_ATTEMPTS,
+		Backoff:     DEFAULT_RETRY_BACKOFF,
+		MaxBackoff:  DEFAULT_RETRY_MAX_BACKOFF,
+	}
+}
+
+//Sets how LoadACL, SetACL and the operations built on them, bulk ones
+//included, retry transient errors. WalkACLs takes WalkOptions.Retry instead
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+//Runs op on path, retrying it as the policy allows. Gives up early, with the
+//last error, once ctx is done, or with a StaleSkipError when path no longer
+//names want (see relookup) after ESTALE
+func (p RetryPolicy) do(ctx context.Context, path string, want fs.FileInfo, log Logger, op func() error) error {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !IsTransient(err) {
+			return err
+		}
+		if IsStale(err) {
+			if skipErr := relookup(path, want, err); skipErr != nil {
+				return skipErr
+			}
+		}
+		if attempt >= p.MaxAttempts {
+			return err
+		}
+		log.Debugf("nfs4acl: %s: %v, retrying in %v", path, err, backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+		if p.MaxBackoff > 0 {
+			backoff = min(backoff, p.MaxBackoff)
+		}
+	}
+}
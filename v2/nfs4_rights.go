@@ -0,0 +1,218 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+)
+
+//Permission classes of a RightsMatrix
+const (
+	RIGHTS_READ  = "read"
+	RIGHTS_WRITE = "write"
+	RIGHTS_ADMIN = "admin"
+)
+
+//The permission classes in display order
+var RightsClasses = []string{RIGHTS_READ, RIGHTS_WRITE, RIGHTS_ADMIN}
+
+//Access mask bits making up each permission class. A who holds a class on a
+//path when granted any of its bits. The attribute bits nearly every ACE
+//grants are left out, so that read means reading the data
+var RightsClassMasks = map[string]AccessMask{
+	RIGHTS_READ:  NFS4_ACE_READ_DATA | NFS4_ACE_EXECUTE,
+	RIGHTS_WRITE: NFS4_ACE_WRITE_DATA | NFS4_ACE_APPEND_DATA | NFS4_ACE_DELETE_CHILD | NFS4_ACE_DELETE,
+	RIGHTS_ADMIN: NFS4_ACE_WRITE_ACL | NFS4_ACE_WRITE_OWNER,
+}
+
+//A who as named by ACEs. Group tells a named group from a user of the same
+//name
+type RightsPrincipal struct {
+	Who   string
+	Group bool
+}
+
+//Renders the principal as its who, named groups prefixed with "group:"
+func (p RightsPrincipal) String() string {
+	if p.Group {
+		return "group:" + p.Who
+	}
+	return p.Who
+}
+
+//RightsMatrix counts, per principal and permission class, the paths where
+//the ACEs naming the principal grant the class. Only the principal's own
+//ACEs are considered, in order, the first to mention a bit deciding it: access
+//through group membership or EVERYONE@ isn't resolved, as that depends on
+//who asks. Add may be called concurrently
+type RightsMatrix struct {
+	Scanned int
+	Counts  map[RightsPrincipal]map[string]int
+
+	paths map[RightsPrincipal]map[string][]string //only when keeping paths
+	mu    sync.Mutex
+}
+
+//Creates an empty matrix, to be filled with Add. With keepPaths the paths
+//behind every count are remembered for Paths, which costs memory on big trees
+func NewRightsMatrix(keepPaths bool) *RightsMatrix {
+	m := &RightsMatrix{Counts: make(map[RightsPrincipal]map[string]int)}
+	if keepPaths {
+		m.paths = make(map[RightsPrincipal]map[string][]string)
+	}
+	return m
+}
+
+//Builds the matrix of every ACL of the tree rooted at root. Paths whose ACL
+//can't be read are left out. keepPaths is as for NewRightsMatrix
+func ScanRights(root string, opts WalkOptions, keepPaths bool) (*RightsMatrix, error) {
+	return ScanRightsContext(context.Background(), root, opts, keepPaths)
+}
+
+//Same as ScanRights, but stops as soon as ctx is done and returns ctx.Err()
+func ScanRightsContext(ctx context.Context, root string, opts WalkOptions, keepPaths bool) (*RightsMatrix, error) {
+	m := NewRightsMatrix(keepPaths)
+	err := WalkACLsContext(ctx, root, opts, func(path string, info fs.FileInfo, acl *ACL, err error) error {
+		if err == nil {
+			m.Add(path, acl)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+//Counts the classes each principal named by acl holds on path
+func (m *RightsMatrix) Add(path string, acl *ACL) {
+	granted := grantedByWho(acl)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Scanned++
+	for principal, mask := range granted {
+		counts := m.Counts[principal]
+		if counts == nil {
+			counts = make(map[string]int)
+			m.Counts[principal] = counts
+		}
+		for _, class := range RightsClasses {
+			if mask&RightsClassMasks[class] == 0 {
+				continue
+			}
+			counts[class]++
+			if m.paths != nil {
+				if m.paths[principal] == nil {
+					m.paths[principal] = make(map[string][]string)
+				}
+				m.paths[principal][class] = append(m.paths[principal][class], path)
+			}
+		}
+	}
+}
+
+//Computes the bits each principal's own ACEs grant, skipping inherit-only
+//ACEs as they don't apply to the path itself
+func grantedByWho(acl *ACL) map[RightsPrincipal]AccessMask {
+	decided := make(map[RightsPrincipal]AccessMask)
+	granted := make(map[RightsPrincipal]AccessMask)
+	for _, ace := range acl.aceList {
+		if ace.Flags&NFS4_ACE_INHERIT_ONLY_ACE != 0 {
+			continue
+		}
+		if ace.AceType != NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE && ace.AceType != NFS4_ACE_ACCESS_DENIED_ACE_TYPE {
+			continue
+		}
+
+		principal := RightsPrincipal{
+			Who:   ace.Who,
+			Group: ace.WhoType == NFS4_ACL_WHO_NAMED && ace.Flags&NFS4_ACE_IDENTIFIER_GROUP != 0,
+		}
+		bits := ace.AccessMask &^ decided[principal]
+		if ace.AceType == NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE {
+			granted[principal] |= bits
+		}
+		decided[principal] |= bits
+	}
+
+	return granted
+}
+
+//Returns the principals of the matrix, sorted by who
+func (m *RightsMatrix) Principals() []RightsPrincipal {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	principals := make([]RightsPrincipal, 0, len(m.Counts))
+	for principal := range m.Counts {
+		principals = append(principals, principal)
+	}
+	sort.Slice(principals, func(i, j int) bool {
+		if principals[i].Who != principals[j].Who {
+			return principals[i].Who < principals[j].Who
+		}
+		return !principals[i].Group
+	})
+	return principals
+}
+
+//Returns the paths where principal holds class, in the order they were
+//added. Nil unless the matrix keeps paths
+func (m *RightsMatrix) Paths(principal RightsPrincipal, class string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.paths[principal][class]
+}
+
+//Writes the matrix as an aligned table, one principal per row and one
+//column per class
+func (m *RightsMatrix) WriteTable(w io.Writer) error {
+	out := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprint(out, "PRINCIPAL")
+	for _, class := range RightsClasses {
+		fmt.Fprintf(out, "\t%s", class)
+	}
+	fmt.Fprintln(out)
+
+	for _, principal := range m.Principals() {
+		fmt.Fprint(out, principal)
+		for _, class := range RightsClasses {
+			fmt.Fprintf(out, "\t%d", m.count(principal, class))
+		}
+		fmt.Fprintln(out)
+	}
+	return out.Flush()
+}
+
+//Writes the matrix as CSV with a principal,group,class,paths header, one row
+//per principal and class
+func (m *RightsMatrix) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"principal", "group", "class", "paths"})
+	for _, principal := range m.Principals() {
+		for _, class := range RightsClasses {
+			out.Write([]string{principal.Who, strconv.FormatBool(principal.Group), class,
+				strconv.Itoa(m.count(principal, class))})
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+func (m *RightsMatrix) count(principal RightsPrincipal, class string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Counts[principal][class]
+}
@@ -0,0 +1,427 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//Parses a single ACE in the type:flags:who:perms form printed by nfs4_getfacl,
+//e.g. "A:fd:bob@example.com:rwax". As with nfs4_ace_from_string, isDir tells
+//the parser whether the ACE is destined for a directory, which decides what
+//the generic W permission expands to
+func ParseACE(spec string, isDir bool) (*ACE, error) {
+	fields := strings.Split(strings.TrimSpace(spec), ":")
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("invalid ace %q: expected type:flags:who:perms", spec)
+	}
+
+	aceType, err := ParseAceType(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ace %q: %v", spec, err)
+	}
+
+	flags, err := ParseFlags(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ace %q: %v", spec, err)
+	}
+	//only the flags that go over the wire can be set, the OWNER@/GROUP@/EVERYONE@
+	//pseudo flags are derived from the who
+	if flags&(NFS4_ACE_OWNER|NFS4_ACE_GROUP|NFS4_ACE_EVERYONE) != 0 {
+		return nil, fmt.Errorf("invalid ace %q: O, G and E flags can't be set directly", spec)
+	}
+
+	who := normalizeWho(fields[2])
+	if err = ValidateWho(who); err != nil {
+		return nil, fmt.Errorf("invalid ace %q: %v", spec, err)
+	}
+
+	mask, err := parsePerms(fields[3], isDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ace %q: %v", spec, err)
+	}
+
+	return NewACE(aceType, flags, mask, who), nil
+}
+
+//Parses an ACL spec: a list of ACEs separated by commas or whitespace, as
+//accepted by nfs4_setfacl. Lines starting with '#' are comments
+func ParseACLSpec(spec string, isDir bool) ([]*ACE, error) {
+	var aces []*ACE
+	for _, aceSpec := range SplitACLSpec(spec) {
+		ace, err := ParseACE(aceSpec, isDir)
+		if err != nil {
+			return nil, err
+		}
+		aces = append(aces, ace)
+	}
+
+	if len(aces) == 0 {
+		return nil, fmt.Errorf("empty acl spec")
+	}
+	return aces, nil
+}
+
+//PathSpec is one section of a spec file: the ACL spec for Path, or, when
+//Path is empty, for the paths the caller names elsewhere
+type PathSpec struct {
+	Path string
+	Spec string
+}
+
+//Parses a spec file as read by nfs4_setfacl -S. ACEs are listed as in an ACL
+//spec, and "# file: path" lines start a section for that path, so one file
+//can declare the ACLs of a whole tree. ACEs before the first header form a
+//section with an empty Path. nfs4_getfacl dumps are valid spec files. Every
+//section is checked with ParseACLSpec, file semantics
+func ParseSpecFile(r io.Reader) ([]PathSpec, error) {
+	var specs []PathSpec
+	var lines []string
+	current := PathSpec{}
+	started := false //whether current has a header or any ACEs
+	flush := func() error {
+		if !started {
+			return nil
+		}
+		current.Spec = strings.Join(lines, "\n")
+		if _, err := ParseACLSpec(current.Spec, false); err != nil {
+			if current.Path == "" {
+				return err
+			}
+			return fmt.Errorf("%s: %v", current.Path, err)
+		}
+		specs = append(specs, current)
+		return nil
+	}
+
+	logical, err := specLines(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range logical {
+		if strings.HasPrefix(line, "# file:") {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			current = PathSpec{Path: strings.TrimSpace(strings.TrimPrefix(line, "# file:"))}
+			lines = nil
+			started = true
+			continue
+		}
+
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+			started = true
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return specs, nil
+}
+
+//Splits an ACL spec into its individual ACE strings without parsing them.
+//Blank lines are dropped, '#' starts a comment running to the end of the line
+//when it begins a line or follows a separator, and a line ending in a
+//backslash continues on the next one
+func SplitACLSpec(spec string) []string {
+	//reading from a string can't fail
+	lines, _ := specLines(strings.NewReader(spec))
+
+	var aces []string
+	for _, line := range lines {
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		for _, field := range fields {
+			if strings.HasPrefix(field, "#") {
+				break
+			}
+			aces = append(aces, field)
+		}
+	}
+
+	return aces
+}
+
+//Reads the logical lines of a spec: trimmed, with backslash continued lines
+//joined. Comment lines are returned as is and never continued, so that a
+//commented out ACE can't swallow the next line
+func specLines(r io.Reader) ([]string, error) {
+	var lines []string
+	pending := ""
+	continued := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !continued && strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+			continue
+		}
+
+		line = pending + line
+		if strings.HasSuffix(line, "\\") {
+			pending = strings.TrimSuffix(line, "\\")
+			continued = true
+			continue
+		}
+		lines = append(lines, line)
+		pending, continued = "", false
+	}
+	if continued {
+		//a backslash on the last line has nothing to join
+		lines = append(lines, pending)
+	}
+
+	return lines, scanner.Err()
+}
+
+//Parses an ace type given either as its display character ("A") or as the
+//word printed in verbose mode ("ALLOW"). Case is ignored
+func ParseAceType(s string) (AceType, error) {
+	switch strings.ToUpper(s) {
+	case string(TYPE_ALLOW), "ALLOW":
+		return NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, nil
+	case string(TYPE_DENY), "DENY":
+		return NFS4_ACE_ACCESS_DENIED_ACE_TYPE, nil
+	case string(TYPE_AUDIT), "AUDIT":
+		return NFS4_ACE_SYSTEM_AUDIT_ACE_TYPE, nil
+	case string(TYPE_ALARM), "ALARM":
+		return NFS4_ACE_SYSTEM_ALARM_ACE_TYPE, nil
+	}
+
+	return 0, fmt.Errorf("unknown ace type %q", s)
+}
+
+//Renders an ace type as its display character, or as a word when verbose.
+//Unknown types render as an empty string
+func AceTypeString(aceType AceType, verbose bool) string {
+	switch aceType {
+	case NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE:
+		if verbose {
+			return "ALLOW"
+		}
+		return string(TYPE_ALLOW)
+	case NFS4_ACE_ACCESS_DENIED_ACE_TYPE:
+		if verbose {
+			return "DENY"
+		}
+		return string(TYPE_DENY)
+	case NFS4_ACE_SYSTEM_AUDIT_ACE_TYPE:
+		if verbose {
+			return "AUDIT"
+		}
+		return string(TYPE_AUDIT)
+	case NFS4_ACE_SYSTEM_ALARM_ACE_TYPE:
+		if verbose {
+			return "ALARM"
+		}
+		return string(TYPE_ALARM)
+	}
+
+	return ""
+}
+
+//Parses flag letters such as "fdi" into ace flags. Every letter FlagsString
+//renders is accepted, including the OWNER@/GROUP@/EVERYONE@ pseudo flags
+func ParseFlags(s string) (AceFlags, error) {
+	var flags AceFlags
+	for _, c := range s {
+		switch c {
+		case FLAG_FILE_INHERIT:
+			flags |= NFS4_ACE_FILE_INHERIT_ACE
+		case FLAG_DIR_INHERIT:
+			flags |= NFS4_ACE_DIRECTORY_INHERIT_ACE
+		case FLAG_NO_PROPAGATE_INHERIT:
+			flags |= NFS4_ACE_NO_PROPAGATE_INHERIT_ACE
+		case FLAG_INHERIT_ONLY:
+			flags |= NFS4_ACE_INHERIT_ONLY_ACE
+		case FLAG_SUCCESSFUL_ACCESS:
+			flags |= NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG
+		case FLAG_FAILED_ACCESS:
+			flags |= NFS4_ACE_FAILED_ACCESS_ACE_FLAG
+		case FLAG_GROUP:
+			flags |= NFS4_ACE_IDENTIFIER_GROUP
+		case FLAG_INHERITED:
+			flags |= NFS4_ACE_INHERITED_ACE
+		case FLAG_OWNER_AT:
+			flags |= NFS4_ACE_OWNER
+		case FLAG_GROUP_AT:
+			flags |= NFS4_ACE_GROUP
+		case FLAG_EVERYONE_AT:
+			flags |= NFS4_ACE_EVERYONE
+		default:
+			return 0, fmt.Errorf("unknown flag %q", c)
+		}
+	}
+
+	return flags, nil
+}
+
+//Renders ace flags as the letters printed by nfs4_getfacl
+func FlagsString(flags AceFlags) string {
+	var buffer bytes.Buffer
+
+	if flags&NFS4_ACE_FILE_INHERIT_ACE != 0 {
+		buffer.WriteRune(FLAG_FILE_INHERIT)
+	}
+	if flags&NFS4_ACE_DIRECTORY_INHERIT_ACE != 0 {
+		buffer.WriteRune(FLAG_DIR_INHERIT)
+	}
+	if flags&NFS4_ACE_NO_PROPAGATE_INHERIT_ACE != 0 {
+		buffer.WriteRune(FLAG_NO_PROPAGATE_INHERIT)
+	}
+	if flags&NFS4_ACE_INHERIT_ONLY_ACE != 0 {
+		buffer.WriteRune(FLAG_INHERIT_ONLY)
+	}
+	if flags&NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG != 0 {
+		buffer.WriteRune(FLAG_SUCCESSFUL_ACCESS)
+	}
+	if flags&NFS4_ACE_FAILED_ACCESS_ACE_FLAG != 0 {
+		buffer.WriteRune(FLAG_FAILED_ACCESS)
+	}
+	if flags&NFS4_ACE_IDENTIFIER_GROUP != 0 {
+		buffer.WriteRune(FLAG_GROUP)
+	}
+	if flags&NFS4_ACE_INHERITED_ACE != 0 {
+		buffer.WriteRune(FLAG_INHERITED)
+	}
+	if flags&NFS4_ACE_OWNER != 0 {
+		buffer.WriteRune(FLAG_OWNER_AT)
+	}
+	if flags&NFS4_ACE_GROUP != 0 {
+		buffer.WriteRune(FLAG_GROUP_AT)
+	}
+	if flags&NFS4_ACE_EVERYONE != 0 {
+		buffer.WriteRune(FLAG_EVERYONE_AT)
+	}
+
+	return buffer.String()
+}
+
+//Parses permission letters such as "rwaxtc" into an access mask. Both the
+//file and directory letters are accepted; the generic W expands with file
+//semantics, use ParseACE to get the directory expansion
+func ParsePerms(s string) (AccessMask, error) {
+	return parsePerms(s, false)
+}
+
+//Renders an access mask as the permission letters printed by nfs4_getfacl.
+//isDir selects the directory letters for the bits they share with files.
+//Bits without a letter are not rendered
+func PermsString(mask AccessMask, isDir bool) string {
+	var buffer bytes.Buffer
+
+	if isDir {
+		if mask&NFS4_ACE_LIST_DIRECTORY != 0 {
+			buffer.WriteRune(PERM_LIST_DIR)
+		}
+		if mask&NFS4_ACE_ADD_FILE != 0 {
+			buffer.WriteRune(PERM_CREATE_FILE)
+		}
+		if mask&NFS4_ACE_ADD_SUBDIRECTORY != 0 {
+			buffer.WriteRune(PERM_CREATE_SUBDIR)
+		}
+		if mask&NFS4_ACE_DELETE_CHILD != 0 {
+			buffer.WriteRune(PERM_DELETE_CHILD)
+		}
+	} else {
+		if mask&NFS4_ACE_READ_DATA != 0 {
+			buffer.WriteRune(PERM_READ_DATA)
+		}
+		if mask&NFS4_ACE_WRITE_DATA != 0 {
+			buffer.WriteRune(PERM_WRITE_DATA)
+		}
+		if mask&NFS4_ACE_APPEND_DATA != 0 {
+			buffer.WriteRune(PERM_APPEND_DATA)
+		}
+	}
+	if mask&NFS4_ACE_DELETE != 0 {
+		buffer.WriteRune(PERM_DELETE)
+	}
+	if mask&NFS4_ACE_EXECUTE != 0 {
+		buffer.WriteRune(PERM_EXECUTE)
+	}
+	if mask&NFS4_ACE_READ_ATTRIBUTES != 0 {
+		buffer.WriteRune(PERM_READ_ATTR)
+	}
+	if mask&NFS4_ACE_WRITE_ATTRIBUTES != 0 {
+		buffer.WriteRune(PERM_WRITE_ATTR)
+	}
+	if mask&NFS4_ACE_READ_NAMED_ATTRS != 0 {
+		buffer.WriteRune(PERM_READ_NAMED_ATTR)
+	}
+	if mask&NFS4_ACE_WRITE_NAMED_ATTRS != 0 {
+		buffer.WriteRune(PERM_WRITE_NAMED_ATTR)
+	}
+	if mask&NFS4_ACE_READ_ACL != 0 {
+		buffer.WriteRune(PERM_READ_ACL)
+	}
+	if mask&NFS4_ACE_WRITE_ACL != 0 {
+		buffer.WriteRune(PERM_WRITE_ACL)
+	}
+	if mask&NFS4_ACE_WRITE_OWNER != 0 {
+		buffer.WriteRune(PERM_WRITE_OWNER)
+	}
+	if mask&NFS4_ACE_SYNCHRONIZE != 0 {
+		buffer.WriteRune(PERM_SYNCHRONIZE)
+	}
+
+	return buffer.String()
+}
+
+//Directory letters share their bits with the file letters (r/w/a), so the
+//same table serves both. Only the generic W differs between the two
+func parsePerms(s string, isDir bool) (AccessMask, error) {
+	var mask AccessMask
+	for _, c := range s {
+		switch c {
+		case PERM_READ_DATA:
+			mask |= NFS4_ACE_READ_DATA
+		case PERM_WRITE_DATA:
+			mask |= NFS4_ACE_WRITE_DATA
+		case PERM_APPEND_DATA:
+			mask |= NFS4_ACE_APPEND_DATA
+		case PERM_DELETE_CHILD:
+			mask |= NFS4_ACE_DELETE_CHILD
+		case PERM_DELETE:
+			mask |= NFS4_ACE_DELETE
+		case PERM_EXECUTE:
+			mask |= NFS4_ACE_EXECUTE
+		case PERM_READ_ATTR:
+			mask |= NFS4_ACE_READ_ATTRIBUTES
+		case PERM_WRITE_ATTR:
+			mask |= NFS4_ACE_WRITE_ATTRIBUTES
+		case PERM_READ_NAMED_ATTR:
+			mask |= NFS4_ACE_READ_NAMED_ATTRS
+		case PERM_WRITE_NAMED_ATTR:
+			mask |= NFS4_ACE_WRITE_NAMED_ATTRS
+		case PERM_READ_ACL:
+			mask |= NFS4_ACE_READ_ACL
+		case PERM_WRITE_ACL:
+			mask |= NFS4_ACE_WRITE_ACL
+		case PERM_WRITE_OWNER:
+			mask |= NFS4_ACE_WRITE_OWNER
+		case PERM_SYNCHRONIZE:
+			mask |= NFS4_ACE_SYNCHRONIZE
+		case PERM_GENERIC_READ:
+			mask |= GenericReadMask(isDir)
+		case PERM_GENERIC_WRITE:
+			mask |= GenericWriteMask(isDir)
+		case PERM_GENERIC_EXECUTE:
+			mask |= GenericExecuteMask(isDir)
+		default:
+			return 0, fmt.Errorf("unknown permission %q", c)
+		}
+	}
+
+	return mask, nil
+}
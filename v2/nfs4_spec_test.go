@@ -0,0 +1,74 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl_test
+
+import (
+	"github.com/cclose/libnfs4acl-go/v2"
+	"github.com/cclose/libnfs4acl-go/v2/nfs4acltest"
+	"testing"
+)
+
+func TestParseACE(t *testing.T) {
+	ace, err := nfs4acl.ParseACE("D:fdg:staff@example.com:rwx", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ace.AceType != nfs4acl.NFS4_ACE_ACCESS_DENIED_ACE_TYPE {
+		t.Errorf("type %v, want DENIED", ace.AceType)
+	}
+	wantFlags := nfs4acl.NFS4_ACE_FILE_INHERIT_ACE | nfs4acl.NFS4_ACE_DIRECTORY_INHERIT_ACE | nfs4acl.NFS4_ACE_IDENTIFIER_GROUP
+	if ace.Flags != wantFlags {
+		t.Errorf("flags %#x, want %#x", uint32(ace.Flags), uint32(wantFlags))
+	}
+	wantMask := nfs4acl.NFS4_ACE_LIST_DIRECTORY | nfs4acl.NFS4_ACE_ADD_FILE | nfs4acl.NFS4_ACE_EXECUTE
+	if ace.AccessMask != wantMask {
+		t.Errorf("mask %#x, want %#x", uint32(ace.AccessMask), uint32(wantMask))
+	}
+	if ace.Who != "staff@example.com" || ace.WhoType != nfs4acl.NFS4_ACL_WHO_NAMED {
+		t.Errorf("who %q of type %d", ace.Who, ace.WhoType)
+	}
+}
+
+func TestParseACEFormatRoundTrip(t *testing.T) {
+	for _, spec := range []string{
+		"A::OWNER@:rwatTnNcCoy",
+		"D:g:GROUP@:wa",
+		"A:fdni:EVERYONE@:rtncy",
+		"U:SF:alice@example.com:rw",
+		"A:I:1000:x",
+	} {
+		ace, err := nfs4acl.ParseACE(spec, false)
+		if err != nil {
+			t.Errorf("%s: %v", spec, err)
+			continue
+		}
+		if got := ace.Format(false, false); got != spec {
+			t.Errorf("%s formats as %s", spec, got)
+		}
+	}
+}
+
+func TestParseACEErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"A::OWNER@",
+		"Q::OWNER@:r",
+		"A:z:OWNER@:r",
+		"A::OWNER@:rq",
+		"A:::r",
+	} {
+		if _, err := nfs4acl.ParseACE(spec, false); err == nil {
+			t.Errorf("%q accepted", spec)
+		}
+	}
+}
+
+func TestParseACLSpec(t *testing.T) {
+	aces, err := nfs4acl.ParseACLSpec("A::OWNER@:rw, A:g:GROUP@:r,", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nfs4acltest.AssertEqual(t, nfs4acl.NewACL(false, aces...), nfs4acl.NewACL(false,
+		nfs4acl.NewACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, nfs4acl.NFS4_ACE_READ_DATA|nfs4acl.NFS4_ACE_WRITE_DATA, nfs4acl.NFS4_ACL_WHO_OWNER_STRING),
+		nfs4acl.NewACE(nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, nfs4acl.NFS4_ACE_IDENTIFIER_GROUP, nfs4acl.NFS4_ACE_READ_DATA, nfs4acl.NFS4_ACL_WHO_GROUP_STRING)))
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+//Reasons of a StaleSkipError
+const (
+	STALE_REMOVED  = "removed"
+	STALE_REPLACED = "replaced"
+)
+
+//StaleSkipError reports a path left alone because its file handle went stale
+//(ESTALE) and, looked up again, the path no longer names the file whose ACL
+//was read: it was removed, or replaced by another file. Writing the ACL
+//computed for the old file over the new one's would lose the latter's ACEs.
+//Unwraps to the ESTALE error
+type StaleSkipError struct {
+	Path   string
+	Reason string //STALE_REMOVED or STALE_REPLACED
+	Err    error
+}
+
+func (e *StaleSkipError) Error() string {
+	return fmt.Sprintf("%s: skipped, file %s while its acl was being changed", e.Path, e.Reason)
+}
+
+func (e *StaleSkipError) Unwrap() error {
+	return e.Err
+}
+
+//Reports whether err is, or wraps, a *StaleSkipError
+func IsStaleSkip(err error) bool {
+	var skip *StaleSkipError
+	return errors.As(err, &skip)
+}
+
+//Looks path up again after staleErr, an ESTALE, to tell whether retrying by
+//path reaches the same file. want is the file as found before, nil if any
+//file will do. Files are told apart by inode and, since a replacement may
+//reuse the inode number, by size for regular files. When the lookup fails for
+//another reason than the path being gone nothing is known, and staleErr is
+//returned as is
+func relookup(path string, want fs.FileInfo, staleErr error) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &StaleSkipError{Path: path, Reason: STALE_REMOVED, Err: staleErr}
+	}
+	if err != nil {
+		return staleErr
+	}
+	if want == nil {
+		return nil
+	}
+
+	if !os.SameFile(info, want) || (want.Mode().IsRegular() && info.Size() != want.Size()) {
+		return &StaleSkipError{Path: path, Reason: STALE_REPLACED, Err: staleErr}
+	}
+	return nil
+}
+
+//Makes SetACL treat path as replaced unless, after ESTALE, it still names the
+//file info describes. Used by bulk operations, which know what they read
+func withExpectedFile(info fs.FileInfo) Option {
+	return func(o *options) {
+		o.expect = info
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestRelookup(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := syscall.ESTALE
+
+	if err := relookup(file, info, stale); err != nil {
+		t.Errorf("same file: %v", err)
+	}
+	if err := relookup(file, nil, stale); err != nil {
+		t.Errorf("any file: %v", err)
+	}
+
+	var skip *StaleSkipError
+	err = relookup(filepath.Join(dir, "gone"), info, stale)
+	if !errors.As(err, &skip) || skip.Reason != STALE_REMOVED || !errors.Is(err, stale) {
+		t.Errorf("missing path: %v", err)
+	}
+
+	if err := os.WriteFile(file, []byte("longer data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = relookup(file, info, stale)
+	if !errors.As(err, &skip) || skip.Reason != STALE_REPLACED {
+		t.Errorf("changed file: %v", err)
+	}
+
+	//looking up through a regular file fails with ENOTDIR, which says nothing
+	//about the file being gone
+	if err := relookup(filepath.Join(file, "child"), info, stale); err != stale {
+		t.Errorf("failed lookup: %v", err)
+	}
+}
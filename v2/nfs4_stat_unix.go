@@ -0,0 +1,32 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+//go:build !windows
+
+package nfs4acl
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+//Identifies the file info describes, ok is false if info didn't come from a
+//stat. path is only needed on Windows
+func fileIDOf(path string, info fs.FileInfo) (id fileID, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
+
+//Owning uid and gid of the file, if info came from a stat. ok is false on
+//Windows, where files are owned by SIDs
+func OwnerIDs(info fs.FileInfo) (uid, gid uint32, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return st.Uid, st.Gid, true
+}
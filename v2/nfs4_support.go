@@ -0,0 +1,55 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"errors"
+)
+
+//Result of ACLSupported
+type ACLSupport int
+
+const (
+	ACL_SUPPORT_UNKNOWN ACLSupport = iota
+	ACL_SUPPORTED
+	ACL_NOT_SUPPORTED
+)
+
+func (s ACLSupport) String() string {
+	switch s {
+	case ACL_SUPPORTED:
+		return "supported"
+	case ACL_NOT_SUPPORTED:
+		return "not supported"
+	}
+	return "unknown"
+}
+
+//Cheaply determines whether the filesystem holding path supports the
+//nfs4_acl xattr, with a single size query and, if its error is ambiguous, a
+//statfs. ACL_SUPPORT_UNKNOWN comes with the error that prevented a decision,
+//e.g. a permission error; tools can skip ACL_NOT_SUPPORTED paths silently
+func ACLSupported(path string) (ACLSupport, error) {
+	_, err := nfs4_getxattr(path, nil)
+	switch {
+	case err == nil:
+		return ACL_SUPPORTED, nil
+	case IsNotSupported(err):
+		return ACL_NOT_SUPPORTED, nil
+	case !IsNoACL(err):
+		return ACL_SUPPORT_UNKNOWN, err
+	}
+
+	//xattrs work but there is no nfs4_acl, which NFSv4 always provides. Only
+	//trust that off NFS, where the attribute can't appear later
+	nfs, statErr := isNFS(path)
+	if statErr != nil {
+		return ACL_SUPPORT_UNKNOWN, errors.Join(err, statErr)
+	}
+	if !nfs {
+		return ACL_NOT_SUPPORTED, nil
+	}
+	return ACL_SUPPORT_UNKNOWN, err
+}
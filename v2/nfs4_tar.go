@@ -0,0 +1,51 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"archive/tar"
+)
+
+//PAX record holding the raw ACL xattr, as written by GNU tar and star with
+//--xattrs, so archives stay interchangeable with them
+const PAX_NFS4_ACL_RECORD = "SCHILY.xattr." + NFS4_ACL_XATTR
+
+//Stores acl in the PAX records of hdr, switching the header to the PAX
+//format. Call before tar.Writer.WriteHeader
+func SetTarHeaderACL(hdr *tar.Header, acl *ACL) error {
+	xattr, err := acl.PackXAttr()
+	if err != nil {
+		return err
+	}
+
+	if hdr.PAXRecords == nil {
+		hdr.PAXRecords = make(map[string]string)
+	}
+	hdr.PAXRecords[PAX_NFS4_ACL_RECORD] = string(xattr)
+	hdr.Format = tar.FormatPAX
+	return nil
+}
+
+//Decodes the ACL stored in the PAX records of hdr, as read by
+//tar.Reader.Next. Returns nil and no error when the entry carries no ACL
+func TarHeaderACL(hdr *tar.Header) (*ACL, error) {
+	xattr, ok := hdr.PAXRecords[PAX_NFS4_ACL_RECORD]
+	if !ok {
+		return nil, nil
+	}
+
+	return XAttrLoad([]byte(xattr), hdr.Typeflag == tar.TypeDir)
+}
+
+//Applies the ACL stored in hdr, if any, to path, the file just extracted
+//from the entry
+func ApplyTarHeaderACL(path string, hdr *tar.Header) error {
+	acl, err := TarHeaderACL(hdr)
+	if err != nil || acl == nil {
+		return err
+	}
+
+	return SetACL(path, acl)
+}
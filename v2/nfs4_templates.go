@@ -0,0 +1,59 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+//Masks used by the templates
+const (
+	templateReadMask = NFS4_ACE_READ_DATA | NFS4_ACE_READ_ATTRIBUTES | NFS4_ACE_READ_NAMED_ATTRS |
+		NFS4_ACE_READ_ACL | NFS4_ACE_SYNCHRONIZE
+	templateWriteMask = NFS4_ACE_WRITE_DATA | NFS4_ACE_APPEND_DATA | NFS4_ACE_WRITE_ATTRIBUTES |
+		NFS4_ACE_WRITE_NAMED_ATTRS | NFS4_ACE_DELETE
+	templateFullMask = templateReadMask | templateWriteMask | NFS4_ACE_EXECUTE | NFS4_ACE_DELETE_CHILD |
+		NFS4_ACE_WRITE_ACL | NFS4_ACE_WRITE_OWNER
+)
+
+//Only the owner has access, with full control
+func PrivateOwner(isDir bool) *ACL {
+	return templateBuilder(isDir).
+		Allow(NFS4_ACL_WHO_OWNER_STRING, templateFullMask).
+		ACL()
+}
+
+//The owner has full control and the members of group can read and modify
+//contents, but not change the ACL or ownership. Everyone else has no access
+func GroupCollaborative(group string, isDir bool) *ACL {
+	groupMask := templateReadMask | templateWriteMask | NFS4_ACE_EXECUTE
+	if isDir {
+		groupMask |= NFS4_ACE_DELETE_CHILD
+	}
+
+	return templateBuilder(isDir).
+		Allow(NFS4_ACL_WHO_OWNER_STRING, templateFullMask).
+		AllowGroup(group, groupMask).
+		ACL()
+}
+
+//The owner has full control and everyone can read. Directories can also be
+//traversed and listed
+func PublicReadOnly(isDir bool) *ACL {
+	everyoneMask := templateReadMask
+	if isDir {
+		everyoneMask |= NFS4_ACE_EXECUTE
+	}
+
+	return templateBuilder(isDir).
+		Allow(NFS4_ACL_WHO_OWNER_STRING, templateFullMask).
+		Allow(NFS4_ACL_WHO_EVERYONE_STRING, everyoneMask).
+		ACL()
+}
+
+//Directory templates are inherited by everything created below them
+func templateBuilder(isDir bool) *ACLBuilder {
+	b := Build(isDir)
+	if isDir {
+		b.DefaultFlags(NFS4_ACE_FILE_INHERIT_ACE | NFS4_ACE_DIRECTORY_INHERIT_ACE)
+	}
+	return b
+}
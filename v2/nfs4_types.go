@@ -0,0 +1,60 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+//Type of an ACE, one of the NFS4_ACE_*_ACE_TYPE values
+type AceType uint32
+
+//Flags of an ACE, a set of the NFS4_ACE_* flag bits. Being a distinct type
+//from AccessMask, the two can't be swapped by mistake
+type AceFlags uint32
+
+//Access mask of an ACE, a set of the NFS4_ACE_* permission bits
+type AccessMask uint32
+
+//Renders the type as its letter, e.g. "A"
+func (t AceType) String() string {
+	return AceTypeString(t, false)
+}
+
+//Renders the flags as the letters printed by nfs4_getfacl, see FlagsString
+func (f AceFlags) String() string {
+	return FlagsString(f)
+}
+
+//Reports whether every flag in bits is set
+func (f AceFlags) Has(bits AceFlags) bool {
+	return f&bits == bits
+}
+
+//Returns the flags with bits set
+func (f AceFlags) With(bits AceFlags) AceFlags {
+	return f | bits
+}
+
+//Returns the flags with bits cleared
+func (f AceFlags) Without(bits AceFlags) AceFlags {
+	return f &^ bits
+}
+
+//Renders the mask as permission letters with file semantics, see PermsString
+func (m AccessMask) String() string {
+	return PermsString(m, false)
+}
+
+//Reports whether every permission in bits is granted
+func (m AccessMask) Has(bits AccessMask) bool {
+	return m&bits == bits
+}
+
+//Returns the mask with bits set
+func (m AccessMask) With(bits AccessMask) AccessMask {
+	return m | bits
+}
+
+//Returns the mask with bits cleared
+func (m AccessMask) Without(bits AccessMask) AccessMask {
+	return m &^ bits
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"errors"
+	"fmt"
+)
+
+//Checks the ACL for flag combinations RFC 5661 gives no meaning:
+//
+// - INHERIT_ONLY or NO_PROPAGATE_INHERIT without FILE_INHERIT or
+//   DIRECTORY_INHERIT
+// - inheritance flags in the ACL of a file
+// - SUCCESSFUL_ACCESS or FAILED_ACCESS on ALLOW and DENY ACEs
+// - the OWNER@, GROUP@ or EVERYONE@ pseudo flags on an ACE for another who
+// - access mask bits outside NFS4_ACE_MASK_ALL
+//
+//All problems are reported, joined into one error. FixFlags repairs them
+func (acl *ACL) ValidateFlags() error {
+	var errs []error
+	for i, ace := range acl.aceList {
+		problems, _, _ := checkFlags(&ace, acl.isDirectory)
+		if undefined := ace.AccessMask &^ NFS4_ACE_MASK_ALL; undefined != 0 {
+			problems = append(problems, fmt.Sprintf("undefined access mask bits %#x", uint32(undefined)))
+		}
+		for _, problem := range problems {
+			errs = append(errs, fmt.Errorf("ace %d (%s): %s", i+1, ace.Format(false, acl.isDirectory), problem))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+//Repairs what ValidateFlags reports without changing who gets access: ACEs
+//that only apply to children but can't be inherited are removed, and the
+//meaningless flags and undefined mask bits are cleared from the rest. Reports
+//whether anything changed
+func (acl *ACL) FixFlags() bool {
+	changed := false
+	kept := acl.aceList[:0]
+	for _, ace := range acl.aceList {
+		problems, fixed, drop := checkFlags(&ace, acl.isDirectory)
+		if len(problems) > 0 {
+			changed = true
+		}
+		if drop {
+			continue
+		}
+		if ace.AccessMask&^NFS4_ACE_MASK_ALL != 0 {
+			changed = true
+		}
+		ace.Flags = fixed
+		ace.AccessMask &= NFS4_ACE_MASK_ALL
+		kept = append(kept, ace)
+	}
+
+	acl.aceList = kept
+	return changed
+}
+
+//Lists the flag problems of ace, with the flags that repair them, or whether
+//the ACE has no effect at all and is best dropped
+func checkFlags(ace *ACE, isDir bool) (problems []string, fixed AceFlags, drop bool) {
+	fixed = ace.Flags
+	inherits := ace.Flags&(NFS4_ACE_FILE_INHERIT_ACE|NFS4_ACE_DIRECTORY_INHERIT_ACE) != 0
+
+	switch {
+	case !isDir && ace.Flags&NFS4_ACE_INHERITANCE_FLAGS != 0:
+		problems = append(problems, "inheritance flags on a file")
+		drop = ace.Flags&NFS4_ACE_INHERIT_ONLY_ACE != 0
+		fixed &^= NFS4_ACE_INHERITANCE_FLAGS
+	case !inherits && ace.Flags&NFS4_ACE_INHERIT_ONLY_ACE != 0:
+		problems = append(problems, "INHERIT_ONLY without FILE_INHERIT or DIRECTORY_INHERIT")
+		drop = true
+	}
+	if isDir && !inherits && ace.Flags&NFS4_ACE_NO_PROPAGATE_INHERIT_ACE != 0 {
+		problems = append(problems, "NO_PROPAGATE_INHERIT without FILE_INHERIT or DIRECTORY_INHERIT")
+		fixed &^= NFS4_ACE_NO_PROPAGATE_INHERIT_ACE
+	}
+
+	auditFlags := NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG | NFS4_ACE_FAILED_ACCESS_ACE_FLAG
+	if ace.Flags&auditFlags != 0 &&
+		(ace.AceType == NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE || ace.AceType == NFS4_ACE_ACCESS_DENIED_ACE_TYPE) {
+		problems = append(problems, "SUCCESSFUL_ACCESS or FAILED_ACCESS on an ALLOW or DENY ace")
+		fixed &^= auditFlags
+	}
+
+	pseudoFlags := NFS4_ACE_OWNER | NFS4_ACE_GROUP | NFS4_ACE_EVERYONE
+	var expected AceFlags
+	switch ace.WhoType {
+	case NFS4_ACL_WHO_OWNER:
+		expected = NFS4_ACE_OWNER
+	case NFS4_ACL_WHO_GROUP:
+		expected = NFS4_ACE_GROUP
+	case NFS4_ACL_WHO_EVERYONE:
+		expected = NFS4_ACE_EVERYONE
+	}
+	if pseudo := ace.Flags & pseudoFlags; pseudo != 0 && pseudo != expected {
+		problems = append(problems, fmt.Sprintf("pseudo flags %q don't match the who", FlagsString(pseudo)))
+		fixed &^= pseudoFlags
+	}
+
+	return problems, fixed, drop
+}
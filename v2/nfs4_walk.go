@@ -0,0 +1,255 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//WalkOptions controls how WalkACLs traverses a tree
+type WalkOptions struct {
+	//Fetch ACLs through symbolic links and descend into symlinked directories,
+	//as nfs4_setfacl -L does. Each directory is entered at most once so link
+	//cycles terminate, unless the file system has no file IDs, in which case
+	//set MaxDepth. When false, symlinks are skipped entirely (-P)
+	FollowSymlinks bool
+
+	//Only paths matching at least one of these globs are visited, though
+	//directories are still descended into. A pattern containing a separator
+	//is matched against the path relative to root, otherwise against the
+	//base name
+	Include []string
+	//Paths below root matching any of these globs are skipped, and excluded
+	//directories are not descended into. Matched as Include
+	Exclude []string
+	//Deepest level visited, the children of root being level 1. 0 means no
+	//limit
+	MaxDepth int
+	//Only visit files, or only directories. Directories are descended into
+	//either way
+	FilesOnly bool
+	DirsOnly  bool
+	//Skip files smaller than this many bytes. Directories are not affected
+	MinSize int64
+	//Retry policy for the ACLs WalkACLs fetches. Operations taking Options
+	//ignore it in favour of WithRetryPolicy
+	Retry RetryPolicy
+}
+
+//Checks the options for conflicts and malformed patterns
+func (opts *WalkOptions) validate() error {
+	if opts.FilesOnly && opts.DirsOnly {
+		return errors.New("FilesOnly and DirsOnly are mutually exclusive")
+	}
+	for _, patterns := range [][]string{opts.Include, opts.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("bad pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+//Decides whether path, found while walking root, is handed to the callback
+//and, for a directory, whether the walk descends into it
+func (opts *WalkOptions) filter(root, path string, info fs.FileInfo, isDir bool) (visit, descend bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		//the root is only matched by its own name
+		rel = filepath.Base(path)
+		descend = true
+	} else {
+		depth := strings.Count(rel, string(os.PathSeparator)) + 1
+		if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+			return false, false
+		}
+		if matchAny(opts.Exclude, rel) {
+			return false, false
+		}
+		descend = opts.MaxDepth == 0 || depth < opts.MaxDepth
+	}
+
+	if isDir {
+		visit = !opts.FilesOnly
+	} else {
+		visit = !opts.DirsOnly && (info == nil || info.Size() >= opts.MinSize)
+	}
+	if len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+		visit = false
+	}
+
+	return visit, descend
+}
+
+//Reports whether rel, a path relative to the walk root, matches any of the
+//patterns
+func matchAny(patterns []string, rel string) bool {
+	base := filepath.Base(rel)
+	for _, pattern := range patterns {
+		name := base
+		if strings.ContainsRune(pattern, os.PathSeparator) {
+			name = rel
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+//WalkFunc is called by WalkACLs for every visited path. If err is not nil, the
+//directory could not be read or the ACL could not be fetched and acl is nil.
+//Returning filepath.SkipDir or filepath.SkipAll behaves as with filepath.WalkDir
+type WalkFunc func(path string, info fs.FileInfo, acl *ACL, err error) error
+
+//Walks the tree rooted at root in lexical order, fetching the ACL of every
+//file and directory and handing it to fn. Every path is lstat'ed once for the
+//FileInfo given to fn, and followed symlinks are stat'ed as well
+func WalkACLs(root string, opts WalkOptions, fn WalkFunc) error {
+	return WalkACLsContext(context.Background(), root, opts, fn)
+}
+
+//Same as WalkACLs, but stops as soon as ctx is done and returns ctx.Err().
+//Paths already handed to fn before cancellation are not revisited
+func WalkACLsContext(ctx context.Context, root string, opts WalkOptions, fn WalkFunc) error {
+	return walkPaths(ctx, root, opts, currentLogger(), func(path string, info fs.FileInfo, isDir bool, err error) error {
+		if err != nil {
+			return fn(path, info, nil, err)
+		}
+
+		acl, err := LoadACL(path, isDir, WithRetryPolicy(opts.Retry))
+		return fn(path, info, acl, err)
+	})
+}
+
+//Walks the tree rooted at root, resolving whether each path should be treated
+//as a directory, without fetching any ACLs. Paths left out by the filters of
+//opts never reach fn. Shared by all recursive operations
+func walkPaths(ctx context.Context, root string, opts WalkOptions, log Logger, fn func(path string, info fs.FileInfo, isDir bool, err error) error) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	start := time.Now()
+	defer func() {
+		currentMetrics().WalkDuration(time.Since(start))
+	}()
+
+	//only needed to break symlink cycles
+	var visited map[fileID]bool
+	if opts.FollowSymlinks {
+		visited = make(map[fileID]bool)
+	}
+	//WalkDir swallows SkipAll, so remember it across nested walks
+	skipAll := false
+
+	var walk func(dir string, viaLink bool) error
+	walk = func(dir string, viaLink bool) error {
+		return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if skipAll {
+				return filepath.SkipAll
+			}
+			//the link itself was already reported by the outer walk
+			if viaLink && path == dir {
+				return nil
+			}
+
+			if err != nil {
+				//root doesn't exist or a directory couldn't be read
+				var info fs.FileInfo
+				if d != nil {
+					info, _ = d.Info()
+				}
+				return noteSkipAll(fn(path, info, false, err), &skipAll)
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return noteSkipAll(fn(path, nil, false, err), &skipAll)
+			}
+
+			if d.Type()&fs.ModeSymlink == 0 {
+				visit, descend := opts.filter(root, path, info, d.IsDir())
+				if d.IsDir() && descend && visited != nil {
+					if id, ok := fileIDOf(path, info); ok {
+						visited[id] = true
+					}
+				}
+				if visit {
+					if err = noteSkipAll(fn(path, info, d.IsDir(), nil), &skipAll); err != nil {
+						return err
+					}
+				}
+				if d.IsDir() && !descend {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !opts.FollowSymlinks {
+				log.Debugf("nfs4acl: skipping symlink %s", path)
+				return nil
+			}
+			//we need the target type to read the ACL correctly
+			target, err := os.Stat(path)
+			if err != nil {
+				return noteSkipAll(fn(path, info, false, err), &skipAll)
+			}
+
+			visit, descend := opts.filter(root, path, target, target.IsDir())
+			if visit {
+				err = noteSkipAll(fn(path, info, target.IsDir(), nil), &skipAll)
+			}
+			if err == filepath.SkipDir {
+				//a link is not a directory to WalkDir, so SkipDir would
+				//skip the rest of its parent instead
+				return nil
+			} else if err != nil || !target.IsDir() || !descend {
+				return err
+			}
+
+			//without an ID only MaxDepth and the OS path limit end a cycle
+			if id, ok := fileIDOf(path, target); !ok {
+				log.Debugf("nfs4acl: no file ID for %s, descending without loop detection", path)
+			} else if visited[id] {
+				log.Debugf("nfs4acl: not descending into %s, directory already visited", path)
+				return nil
+			} else {
+				visited[id] = true
+			}
+			//the trailing separator makes WalkDir resolve the link
+			return walk(path+string(os.PathSeparator), true)
+		})
+	}
+
+	return walk(root, false)
+}
+
+//Records whether fn asked to stop the whole walk
+func noteSkipAll(err error, skipAll *bool) error {
+	if err == filepath.SkipAll {
+		*skipAll = true
+	}
+	return err
+}
+
+//Device and inode pair identifying a directory, the volume serial number and
+//file index on Windows
+type fileID struct {
+	dev uint64
+	ino uint64
+}
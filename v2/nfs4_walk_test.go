@@ -0,0 +1,72 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWalkStopsAtSymlinkCycle(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(root, "a", "loop")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	var paths []string
+	err := walkPaths(context.Background(), root, WalkOptions{FollowSymlinks: true}, nopLogger{},
+		func(path string, info fs.FileInfo, isDir bool, err error) error {
+			if err != nil {
+				return err
+			}
+			paths = append(paths, path)
+			if len(paths) > 10 {
+				t.Fatalf("walk didn't stop: %q", paths)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{root, filepath.Join(root, "a"), filepath.Join(root, "a", "loop")}
+	if len(paths) != len(want) {
+		t.Fatalf("visited %q, want %q", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("visited %q, want %q", paths, want)
+			break
+		}
+	}
+}
+
+func TestFileIDOf(t *testing.T) {
+	root := t.TempDir()
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(root, link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	dirInfo, err := os.Stat(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkInfo, err := os.Stat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirID, ok := fileIDOf(root, dirInfo)
+	if !ok || dirID == (fileID{}) {
+		t.Fatalf("no ID for %s", root)
+	}
+	if linkID, ok := fileIDOf(link, linkInfo); !ok || linkID != dirID {
+		t.Errorf("link resolves to %v, directory is %v", linkID, dirID)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"context"
+	"time"
+)
+
+//WatchEvent reports that the ACL of a watched path changed, or that it could
+//no longer be read, in which case Err is set and New and Diff are nil
+type WatchEvent struct {
+	Path string
+	Old  *ACL //nil if the previous read failed
+	New  *ACL
+	Diff *Diff
+	Err  error
+}
+
+//Watcher polls a set of paths and reports ACL changes. NFS servers don't
+//notify clients of attribute changes, so polling the ACL Hash is the only
+//reliable way to notice them
+type Watcher struct {
+	Events chan WatchEvent
+
+	paths    []string
+	interval time.Duration
+}
+
+//Creates a watcher checking paths every interval. Call Run to start it
+func NewWatcher(paths []string, interval time.Duration) *Watcher {
+	return &Watcher{
+		Events:   make(chan WatchEvent, 16),
+		paths:    append([]string(nil), paths...),
+		interval: interval,
+	}
+}
+
+//Records the current ACLs, then polls until ctx is done, delivering an event
+//on Events for every change. A path that fails to read is reported once,
+//until it can be read again. Events is closed when Run returns ctx.Err()
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.Events)
+
+	type state struct {
+		acl  *ACL
+		hash string
+		err  error
+	}
+	states := make(map[string]state, len(w.paths))
+	for _, path := range w.paths {
+		acl, err := GetACL(path)
+		if err != nil {
+			states[path] = state{err: err}
+			continue
+		}
+		states[path] = state{acl: acl, hash: acl.Hash()}
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		for _, path := range w.paths {
+			prev := states[path]
+			acl, err := GetACL(path)
+
+			var event *WatchEvent
+			var next state
+			switch {
+			case err != nil:
+				next = state{err: err}
+				if prev.err == nil {
+					event = &WatchEvent{Path: path, Old: prev.acl, Err: err}
+				}
+			default:
+				next = state{acl: acl, hash: acl.Hash()}
+				if prev.err != nil || next.hash != prev.hash {
+					event = &WatchEvent{Path: path, Old: prev.acl, New: acl}
+					if prev.acl != nil {
+						event.Diff = DiffACLs(prev.acl, acl)
+					}
+				}
+			}
+			states[path] = next
+
+			if event == nil {
+				continue
+			}
+			select {
+			case w.Events <- *event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
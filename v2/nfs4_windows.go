@@ -0,0 +1,293 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/sys/windows"
+	"os"
+	"strings"
+	"unsafe"
+)
+
+//NFSv4 ACLs were modelled on Windows ACLs: the ACE types, the access mask bits
+//and the inheritance flags have the same values on both. Only the audit flags
+//sit at other bits, and whos have to be mapped to SIDs
+
+//Windows ACE values x/sys/windows doesn't define
+const (
+	WINDOWS_ACL_REVISION               = 2
+	WINDOWS_SUCCESSFUL_ACCESS_ACE_FLAG = 0x40
+	WINDOWS_FAILED_ACCESS_ACE_FLAG     = 0x80
+	//every file right, what a NULL DACL grants
+	WINDOWS_FILE_ALL_ACCESS = 0x001F01FF
+)
+
+//Well known SIDs of the special whos. OWNER@ and GROUP@ stand for the owner
+//and group of the security descriptor instead
+var windowsSpecialWhos = map[string]windows.WELL_KNOWN_SID_TYPE{
+	NFS4_ACL_WHO_EVERYONE_STRING:      windows.WinWorldSid,
+	NFS4_ACL_WHO_ANONYMOUS_STRING:     windows.WinAnonymousSid,
+	NFS4_ACL_WHO_AUTHENTICATED_STRING: windows.WinAuthenticatedUserSid,
+	NFS4_ACL_WHO_INTERACTIVE_STRING:   windows.WinInteractiveSid,
+	NFS4_ACL_WHO_NETWORK_STRING:       windows.WinNetworkSid,
+	NFS4_ACL_WHO_DIALUP_STRING:        windows.WinDialupSid,
+	NFS4_ACL_WHO_BATCH_STRING:         windows.WinBatchSid,
+	NFS4_ACL_WHO_SERVICE_STRING:       windows.WinServiceSid,
+}
+
+//Reads the ACL of a local or SMB path as an NFSv4 ACL. Only the DACL is read,
+//the SACL needs SeSecurityPrivilege
+func GetWindowsACL(path string) (*ACL, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.OWNER_SECURITY_INFORMATION|windows.GROUP_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return nil, fmt.Errorf("read security descriptor of %s: %w", path, err)
+	}
+	return SecurityDescriptorToACL(sd, info.IsDir())
+}
+
+//Converts a Windows security descriptor to an ACL, the DACL first and
+//then the SACL, if the descriptor has one. ACEs for the descriptor's owner and
+//group, or for CREATOR OWNER and CREATOR GROUP, become OWNER@ and GROUP@, as
+//multiprotocol servers map them. Other SIDs become user@domain whos, or the
+//SID string when they can't be looked up. A NULL DACL becomes an ACL granting
+//EVERYONE@ everything
+func SecurityDescriptorToACL(sd *windows.SECURITY_DESCRIPTOR, isDir bool) (*ACL, error) {
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return nil, err
+	}
+	group, _, err := sd.Group()
+	if err != nil {
+		return nil, err
+	}
+
+	acl := &ACL{isDirectory: isDir}
+	dacl, _, err := sd.DACL()
+	switch {
+	case errors.Is(err, windows.ERROR_OBJECT_NOT_FOUND) || (err == nil && dacl == nil):
+		acl.AddACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, WINDOWS_FILE_ALL_ACCESS, NFS4_ACL_WHO_EVERYONE_STRING)
+	case err != nil:
+		return nil, err
+	default:
+		if err = appendWindowsACEs(acl, dacl, owner, group); err != nil {
+			return nil, err
+		}
+	}
+
+	if sacl, _, err := sd.SACL(); err == nil && sacl != nil {
+		if err = appendWindowsACEs(acl, sacl, owner, group); err != nil {
+			return nil, err
+		}
+	}
+	return acl, nil
+}
+
+//Converts acl to a self-relative Windows security descriptor. Allow and deny
+//ACEs go to the DACL, audit and alarm ACEs to the SACL, in their original
+//order. OWNER@ and GROUP@ become owner and group, or CREATOR OWNER and
+//CREATOR GROUP on inherit only ACEs, so either may only be nil if the ACL
+//doesn't use it. Numeric whos have no SID and are rejected
+func ACLToSecurityDescriptor(acl *ACL, owner, group *windows.SID) (*windows.SECURITY_DESCRIPTOR, error) {
+	var daclACEs, saclACEs []*ACE
+	for i := range acl.aceList {
+		ace := &acl.aceList[i]
+		switch ace.AceType {
+		case NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, NFS4_ACE_ACCESS_DENIED_ACE_TYPE:
+			daclACEs = append(daclACEs, ace)
+		case NFS4_ACE_SYSTEM_AUDIT_ACE_TYPE, NFS4_ACE_SYSTEM_ALARM_ACE_TYPE:
+			saclACEs = append(saclACEs, ace)
+		default:
+			return nil, fmt.Errorf("unknown ace type %d", ace.AceType)
+		}
+	}
+
+	sd, err := windows.NewSecurityDescriptor()
+	if err != nil {
+		return nil, err
+	}
+	if owner != nil {
+		if err = sd.SetOwner(owner, false); err != nil {
+			return nil, err
+		}
+	}
+	if group != nil {
+		if err = sd.SetGroup(group, false); err != nil {
+			return nil, err
+		}
+	}
+
+	dacl, err := packWindowsACL(daclACEs, owner, group)
+	if err != nil {
+		return nil, err
+	}
+	if err = sd.SetDACL(dacl, true, false); err != nil {
+		return nil, err
+	}
+	if len(saclACEs) > 0 {
+		sacl, err := packWindowsACL(saclACEs, owner, group)
+		if err != nil {
+			return nil, err
+		}
+		if err = sd.SetSACL(sacl, true, false); err != nil {
+			return nil, err
+		}
+	}
+
+	//the self-relative copy holds everything in one block, so the packed
+	//ACLs need not outlive this call
+	return sd.ToSelfRelative()
+}
+
+//Builds a Windows ACL: an 8 byte header followed by each ACE's header, mask
+//and SID, all little endian
+func packWindowsACL(aces []*ACE, owner, group *windows.SID) (*windows.ACL, error) {
+	//room for domain SIDs, the most common kind
+	buf := make([]byte, 8, 8+len(aces)*36)
+	for _, ace := range aces {
+		sid, err := whoToSID(ace, owner, group)
+		if err != nil {
+			return nil, err
+		}
+		sidBytes := unsafe.Slice((*byte)(unsafe.Pointer(sid)), sid.Len())
+
+		buf = append(buf, byte(ace.AceType), toWindowsFlags(ace.Flags))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(8+len(sidBytes)))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(ace.AccessMask))
+		buf = append(buf, sidBytes...)
+	}
+	if len(buf) > 0xFFFF {
+		return nil, fmt.Errorf("acl of %d bytes exceeds the Windows limit of 64KiB", len(buf))
+	}
+
+	buf[0] = WINDOWS_ACL_REVISION
+	binary.LittleEndian.PutUint16(buf[2:], uint16(len(buf)))
+	binary.LittleEndian.PutUint16(buf[4:], uint16(len(aces)))
+	return (*windows.ACL)(unsafe.Pointer(&buf[0])), nil
+}
+
+//Appends the ACEs of a Windows ACL to acl
+func appendWindowsACEs(acl *ACL, winACL *windows.ACL, owner, group *windows.SID) error {
+	for i := 0; i < int(winACL.AceCount); i++ {
+		var winACE *windows.ACCESS_ALLOWED_ACE
+		if err := windows.GetAce(winACL, uint32(i), &winACE); err != nil {
+			return err
+		}
+		//allow, deny, audit and alarm ACEs share one layout, the object
+		//and callback variants have no NFSv4 counterpart
+		if AceType(winACE.Header.AceType) > NFS4_ACE_SYSTEM_ALARM_ACE_TYPE {
+			return fmt.Errorf("ace %d: unsupported Windows ace type %d", i, winACE.Header.AceType)
+		}
+
+		sid := (*windows.SID)(unsafe.Pointer(&winACE.SidStart))
+		who, isGroup := sidToWho(sid, owner, group)
+		flags := fromWindowsFlags(winACE.Header.AceFlags)
+		if isGroup {
+			flags |= NFS4_ACE_IDENTIFIER_GROUP
+		}
+		acl.AddACE(AceType(winACE.Header.AceType), flags, AccessMask(winACE.Mask), who)
+	}
+	return nil
+}
+
+//Maps the who of ace to a SID
+func whoToSID(ace *ACE, owner, group *windows.SID) (*windows.SID, error) {
+	inheritOnly := ace.Flags&NFS4_ACE_INHERIT_ONLY_ACE != 0
+	switch ace.WhoType {
+	case NFS4_ACL_WHO_OWNER:
+		if inheritOnly {
+			return windows.CreateWellKnownSid(windows.WinCreatorOwnerSid)
+		}
+		if owner == nil {
+			return nil, errors.New("OWNER@ ace without an owner sid")
+		}
+		return owner, nil
+	case NFS4_ACL_WHO_GROUP:
+		if inheritOnly {
+			return windows.CreateWellKnownSid(windows.WinCreatorGroupSid)
+		}
+		if group == nil {
+			return nil, errors.New("GROUP@ ace without a group sid")
+		}
+		return group, nil
+	case NFS4_ACL_WHO_NUMERIC:
+		return nil, fmt.Errorf("numeric who %q has no sid", ace.Who)
+	}
+
+	if sidType, ok := windowsSpecialWhos[ace.Who]; ok {
+		return windows.CreateWellKnownSid(sidType)
+	}
+	if strings.HasPrefix(ace.Who, "S-1-") {
+		return windows.StringToSid(ace.Who)
+	}
+	//LookupAccountName takes user@domain as a UPN
+	sid, _, _, err := windows.LookupSID("", ace.Who)
+	if err != nil {
+		return nil, fmt.Errorf("look up %s: %w", ace.Who, err)
+	}
+	return sid, nil
+}
+
+//Maps a SID to a who, and reports whether it names a group
+func sidToWho(sid, owner, group *windows.SID) (string, bool) {
+	switch {
+	case (owner != nil && sid.Equals(owner)) || sid.IsWellKnown(windows.WinCreatorOwnerSid):
+		return NFS4_ACL_WHO_OWNER_STRING, false
+	case (group != nil && sid.Equals(group)) || sid.IsWellKnown(windows.WinCreatorGroupSid):
+		return NFS4_ACL_WHO_GROUP_STRING, false
+	}
+	for who, sidType := range windowsSpecialWhos {
+		if sid.IsWellKnown(sidType) {
+			return who, false
+		}
+	}
+
+	account, domain, accType, err := sid.LookupAccount("")
+	if err != nil {
+		return sid.String(), false
+	}
+	isGroup := accType == windows.SidTypeGroup || accType == windows.SidTypeAlias ||
+		accType == windows.SidTypeWellKnownGroup
+	if domain == "" {
+		return account, isGroup
+	}
+	return account + "@" + domain, isGroup
+}
+
+func toWindowsFlags(flags AceFlags) uint8 {
+	winFlags := uint8(flags & NFS4_ACE_INHERITANCE_FLAGS)
+	if flags&NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG != 0 {
+		winFlags |= WINDOWS_SUCCESSFUL_ACCESS_ACE_FLAG
+	}
+	if flags&NFS4_ACE_FAILED_ACCESS_ACE_FLAG != 0 {
+		winFlags |= WINDOWS_FAILED_ACCESS_ACE_FLAG
+	}
+	if flags&NFS4_ACE_INHERITED_ACE != 0 {
+		winFlags |= windows.INHERITED_ACE
+	}
+	return winFlags
+}
+
+func fromWindowsFlags(winFlags uint8) AceFlags {
+	flags := AceFlags(winFlags) & NFS4_ACE_INHERITANCE_FLAGS
+	if winFlags&WINDOWS_SUCCESSFUL_ACCESS_ACE_FLAG != 0 {
+		flags |= NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG
+	}
+	if winFlags&WINDOWS_FAILED_ACCESS_ACE_FLAG != 0 {
+		flags |= NFS4_ACE_FAILED_ACCESS_ACE_FLAG
+	}
+	if winFlags&windows.INHERITED_ACE != 0 {
+		flags |= NFS4_ACE_INHERITED_ACE
+	}
+	return flags
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acl
+
+import (
+	"golang.org/x/sys/windows"
+	"testing"
+)
+
+func TestWindowsFlagsRoundTrip(t *testing.T) {
+	for flags := AceFlags(0); flags <= 0xFF; flags++ {
+		//the group flag comes from the SID type, not the ACE flags
+		if flags&^nfs4AceWireFlags != 0 || flags&NFS4_ACE_IDENTIFIER_GROUP != 0 {
+			continue
+		}
+		if got := fromWindowsFlags(toWindowsFlags(flags)); got != flags {
+			t.Errorf("flags %#x come back as %#x", uint32(flags), uint32(got))
+		}
+	}
+}
+
+func TestSecurityDescriptorRoundTrip(t *testing.T) {
+	owner, err := windows.CreateWellKnownSid(windows.WinLocalSystemSid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := windows.CreateWellKnownSid(windows.WinBuiltinUsersSid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//allow and deny ACEs come back first, the audit ACE from the SACL last
+	aces, err := ParseACLSpec("D::EVERYONE@:w,"+
+		"A::OWNER@:rwxtTnNcCoy,"+
+		"A::GROUP@:rxtncy,"+
+		"A:fdi:OWNER@:rwx,"+
+		"A:fdiI:GROUP@:rx,"+
+		"A::AUTHENTICATED@:rtncy,"+
+		"U:S:EVERYONE@:w", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl := NewACL(true, aces...)
+
+	sd, err := ACLToSecurityDescriptor(acl, owner, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := SecurityDescriptorToACL(sd, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Hash() != acl.Hash() {
+		t.Errorf("got %v, want %v", back.ACEs(), acl.ACEs())
+	}
+}
+
+func TestACLToSecurityDescriptorRejectsNumericWho(t *testing.T) {
+	acl := NewACL(false, NewACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, NFS4_ACE_READ_DATA, "1000"))
+	if _, err := ACLToSecurityDescriptor(acl, nil, nil); err == nil {
+		t.Error("numeric who accepted")
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func getxattr(path, attr string, value []byte) (int, error) {
+	return unix.Getxattr(path, attr, value)
+}
+
+func setxattr(path, attr string, value []byte, flags int) error {
+	return unix.Setxattr(path, attr, value, flags)
+}
+
+//Reports whether path is on an NFS mount
+func isNFS(path string) (bool, error) {
+	var fs unix.Statfs_t
+	if err := unix.Statfs(path, &fs); err != nil {
+		return false, err
+	}
+	return fs.Type == unix.NFS_SUPER_MAGIC, nil
+}
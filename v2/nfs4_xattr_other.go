@@ -0,0 +1,27 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+//go:build !linux && !(solaris && cgo)
+
+package nfs4acl
+
+import (
+	"syscall"
+)
+
+//Only the Linux NFS client exposes ACLs as the system.nfs4_acl xattr.
+//Elsewhere every path reads as not supported, so the portable parts of the
+//package, e.g. parsing, diffing and the Windows adapter, still build. Solaris
+//has its own backend when built with cgo
+func getxattr(path, attr string, value []byte) (int, error) {
+	return 0, syscall.ENOTSUP
+}
+
+func setxattr(path, attr string, value []byte, flags int) error {
+	return syscall.ENOTSUP
+}
+
+func isNFS(path string) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+//go:build solaris && cgo
+
+package nfs4acl
+
+/*
+#include <stdlib.h>
+#include <sys/acl.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+//Solaris and illumos have no nfs4_acl xattr, ACLs are read and written as
+//ace_t arrays with acl(2), on ZFS and NFSv4 alike. This backend serves them
+//as that xattr, packed in the NFSv4 wire format, so that LoadACL, SetACL and
+//everything built on them work unchanged. The ace_t types, masks and
+//inheritance flags are the NFSv4 ones; the special whos are flags and other
+//whos are uids or gids, mapped with the PrincipalResolver
+
+func getxattr(path, attr string, value []byte) (int, error) {
+	if attr != NFS4_ACL_XATTR {
+		return 0, syscall.ENOTSUP
+	}
+	acl, err := solarisGetACL(path)
+	if err != nil {
+		return 0, err
+	}
+
+	xattr := acl.pack()
+	switch {
+	case len(value) == 0:
+		return len(xattr), nil
+	case len(value) < len(xattr):
+		return 0, syscall.ERANGE
+	}
+	return copy(value, xattr), nil
+}
+
+func setxattr(path, attr string, value []byte, flags int) error {
+	if attr != NFS4_ACL_XATTR {
+		return syscall.ENOTSUP
+	}
+	acl, err := XAttrLoad(value, false)
+	if err != nil {
+		return err
+	}
+	return solarisSetACL(path, acl)
+}
+
+//acl(2) never reports a file without an ACL, so ACLSupported doesn't get
+//as far as asking
+func isNFS(path string) (bool, error) {
+	return false, nil
+}
+
+//Reads the ACL of path, counting its ACEs first. If the ACL keeps growing
+//between counting and reading, gives up after DEFAULT_RANGE_RETRIES attempts
+//with ERANGE, as a getxattr racing a growing ACL would
+func solarisGetACL(path string) (*ACL, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	for attempt := 0; ; attempt++ {
+		count, err := C.acl(cpath, C.ACE_GETACLCNT, 0, nil)
+		if count < 0 {
+			return nil, solarisErrno(err)
+		}
+		aces := make([]C.ace_t, count+1)
+		count, err = C.acl(cpath, C.ACE_GETACL, C.int(len(aces)), unsafe.Pointer(&aces[0]))
+		if count < 0 {
+			//the ACL grew between the two calls
+			if !errors.Is(err, syscall.ENOSPC) {
+				return nil, solarisErrno(err)
+			}
+			if attempt >= DEFAULT_RANGE_RETRIES {
+				return nil, syscall.ERANGE
+			}
+			continue
+		}
+
+		acl := &ACL{}
+		for _, ace := range aces[:count] {
+			acl.aceList = append(acl.aceList, *solarisToNFS4(ace))
+		}
+		return acl, nil
+	}
+}
+
+func solarisSetACL(path string, acl *ACL) error {
+	if len(acl.aceList) == 0 {
+		return errors.New("acl(2) can't set an empty ACL")
+	}
+	aces := make([]C.ace_t, len(acl.aceList))
+	for i := range acl.aceList {
+		solarisACE, err := nfs4ToSolaris(&acl.aceList[i])
+		if err != nil {
+			return err
+		}
+		aces[i] = solarisACE
+	}
+
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if ret, err := C.acl(cpath, C.ACE_SETACL, C.int(len(aces)), unsafe.Pointer(&aces[0])); ret < 0 {
+		return solarisErrno(err)
+	}
+	return nil
+}
+
+//Filesystems without ACE ACLs, e.g. UFS, fail with ENOSYS
+func solarisErrno(err error) error {
+	if errors.Is(err, syscall.ENOSYS) {
+		return syscall.ENOTSUP
+	}
+	return err
+}
+
+func solarisToNFS4(ace C.ace_t) *ACE {
+	flags := AceFlags(ace.a_flags)
+	var who string
+	switch {
+	case flags&C.ACE_OWNER != 0:
+		who = NFS4_ACL_WHO_OWNER_STRING
+	case flags&C.ACE_GROUP != 0:
+		who = NFS4_ACL_WHO_GROUP_STRING
+	case flags&C.ACE_EVERYONE != 0:
+		who = NFS4_ACL_WHO_EVERYONE_STRING
+	default:
+		id := strconv.FormatUint(uint64(ace.a_who), 10)
+		who = ResolveNumericWho(id, flags&NFS4_ACE_IDENTIFIER_GROUP != 0, nil)
+	}
+
+	return NewACE(AceType(ace.a_type), flags&nfs4AceWireFlags, AccessMask(ace.a_access_mask), who)
+}
+
+func nfs4ToSolaris(ace *ACE) (C.ace_t, error) {
+	flags := ace.Flags & nfs4AceWireFlags
+	var id uint64
+	switch ace.WhoType {
+	case NFS4_ACL_WHO_OWNER:
+		flags |= C.ACE_OWNER
+	case NFS4_ACL_WHO_GROUP:
+		flags |= C.ACE_GROUP | NFS4_ACE_IDENTIFIER_GROUP
+	case NFS4_ACL_WHO_EVERYONE:
+		flags |= C.ACE_EVERYONE
+	case NFS4_ACL_WHO_NAMED, NFS4_ACL_WHO_NUMERIC:
+		var err error
+		id, err = strconv.ParseUint(NumericWho(ace.Who, flags&NFS4_ACE_IDENTIFIER_GROUP != 0), 10, 32)
+		if err != nil {
+			return C.ace_t{}, fmt.Errorf("who %q has no local id", ace.Who)
+		}
+	default:
+		return C.ace_t{}, fmt.Errorf("who %s has no Solaris equivalent", ace.Who)
+	}
+
+	return C.ace_t{
+		a_who:         C.uid_t(id),
+		a_access_mask: C.uint32_t(ace.AccessMask),
+		a_flags:       C.uint16_t(flags),
+		a_type:        C.uint16_t(ace.AceType),
+	}, nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+//go:build solaris && cgo
+
+package nfs4acl
+
+import (
+	"errors"
+	"testing"
+)
+
+//Knows no names or ids at all
+type emptyResolver struct{}
+
+func (emptyResolver) LookupUser(name string) (uint32, error) { return 0, errors.New("unknown " + name) }
+func (emptyResolver) LookupGroup(name string) (uint32, error) {
+	return 0, errors.New("unknown " + name)
+}
+func (emptyResolver) UserName(uid uint32) (string, error)  { return "", errors.New("unknown uid") }
+func (emptyResolver) GroupName(gid uint32) (string, error) { return "", errors.New("unknown gid") }
+
+func TestSolarisACERoundTrip(t *testing.T) {
+	SetPrincipalResolver(emptyResolver{})
+	t.Cleanup(func() { SetPrincipalResolver(nil) })
+
+	aces, err := ParseACLSpec("D::EVERYONE@:w,"+
+		"A:fd:OWNER@:rwxtTnNcCoy,"+
+		"A:g:GROUP@:rxtncy,"+
+		"A:fdgiI:GROUP@:rx,"+
+		"A::1000:r,"+
+		"A:g:1001:r,"+
+		"U:S:EVERYONE@:w", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ace := range aces {
+		solarisACE, err := nfs4ToSolaris(ace)
+		if err != nil {
+			t.Errorf("%s: %v", ace.Who, err)
+			continue
+		}
+		back := solarisToNFS4(solarisACE)
+		if *back != *ace {
+			t.Errorf("%+v comes back as %+v", *ace, *back)
+		}
+	}
+}
+
+func TestNFS4ToSolarisRejectsUnknownWho(t *testing.T) {
+	SetPrincipalResolver(emptyResolver{})
+	t.Cleanup(func() { SetPrincipalResolver(nil) })
+
+	ace := NewACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, NFS4_ACE_READ_DATA, "nobody@example.com")
+	if _, err := nfs4ToSolaris(ace); err == nil {
+		t.Error("who without a local id accepted")
+	}
+	ace = NewACE(NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE, 0, NFS4_ACE_READ_DATA, NFS4_ACL_WHO_NETWORK_STRING)
+	if _, err := nfs4ToSolaris(ace); err == nil {
+		t.Error("NETWORK@ accepted")
+	}
+}
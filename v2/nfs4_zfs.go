@@ -0,0 +1,184 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4_acl provides an interface to NFSv4 Access Control Lists
+
+package nfs4acl
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+//Values of the ZFS aclmode property, deciding what chmod does to an ACL
+const (
+	ZFS_ACLMODE_DISCARD     = "discard"
+	ZFS_ACLMODE_GROUPMASK   = "groupmask"
+	ZFS_ACLMODE_PASSTHROUGH = "passthrough"
+	ZFS_ACLMODE_RESTRICTED  = "restricted"
+)
+
+//Values of the ZFS aclinherit property, deciding what new files and
+//directories inherit
+const (
+	ZFS_ACLINHERIT_DISCARD       = "discard"
+	ZFS_ACLINHERIT_NOALLOW       = "noallow"
+	ZFS_ACLINHERIT_RESTRICTED    = "restricted"
+	ZFS_ACLINHERIT_PASSTHROUGH   = "passthrough"
+	ZFS_ACLINHERIT_PASSTHROUGH_X = "passthrough-x"
+)
+
+//ACL related properties of a ZFS dataset
+type ZFSProperties struct {
+	Dataset    string
+	ACLMode    string
+	ACLInherit string
+}
+
+//Reads the properties of the dataset holding path. The default runs zfs get,
+//which only works on the server; NFS clients can plug in one asking the
+//server, or returning known values
+type ZFSPropertySource func(path string) (*ZFSProperties, error)
+
+var (
+	zfsSourceMu sync.RWMutex
+	zfsSource   ZFSPropertySource = zfsGet
+)
+
+//Replaces the source of ZFS properties, nil restores zfs get
+func SetZFSPropertySource(source ZFSPropertySource) {
+	zfsSourceMu.Lock()
+	defer zfsSourceMu.Unlock()
+	if source == nil {
+		source = zfsGet
+	}
+	zfsSource = source
+}
+
+//Returns the ACL properties of the dataset holding path
+func ZFSPropertiesForPath(path string) (*ZFSProperties, error) {
+	zfsSourceMu.RLock()
+	source := zfsSource
+	zfsSourceMu.RUnlock()
+	return source(path)
+}
+
+//Runs zfs get, which accepts a path and reports its dataset
+func zfsGet(path string) (*ZFSProperties, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("zfs", "get", "-H", "-o", "name,property,value", "aclmode,aclinherit", path)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("zfs get %s: %w: %s", path, err, msg)
+		}
+		return nil, fmt.Errorf("zfs get %s: %w", path, err)
+	}
+
+	props := &ZFSProperties{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("zfs get %s: unexpected output %q", path, line)
+		}
+		props.Dataset = fields[0]
+		switch fields[1] {
+		case "aclmode":
+			props.ACLMode = fields[2]
+		case "aclinherit":
+			props.ACLInherit = fields[2]
+		}
+	}
+	return props, nil
+}
+
+//Computes the ACL a child created under parent receives on this dataset.
+//passthrough follows InheritedACL, the others change its result:
+//
+// - discard inherits nothing, ZFS derives the ACL from the mode instead
+// - noallow inherits only the DENY ACEs
+// - restricted, the default, drops WRITE_ACL and WRITE_OWNER
+// - passthrough-x drops EXECUTE on files, assuming, as for most files, the
+//   creating mode has no execute bits
+func (p *ZFSProperties) InheritedACL(parent *ACL, childIsDir bool) *ACL {
+	child := InheritedACL(parent, childIsDir)
+
+	kept := child.aceList[:0]
+	for _, ace := range child.aceList {
+		switch p.ACLInherit {
+		case ZFS_ACLINHERIT_DISCARD:
+			continue
+		case ZFS_ACLINHERIT_NOALLOW:
+			if ace.AceType == NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE {
+				continue
+			}
+		case ZFS_ACLINHERIT_PASSTHROUGH:
+		case ZFS_ACLINHERIT_PASSTHROUGH_X:
+			if !childIsDir {
+				ace.AccessMask &^= NFS4_ACE_EXECUTE
+			}
+		default:
+			ace.AccessMask &^= NFS4_ACE_WRITE_ACL | NFS4_ACE_WRITE_OWNER
+		}
+		kept = append(kept, ace)
+	}
+	child.aceList = kept
+
+	return child
+}
+
+//Lists what this dataset will do to acl once set: ACEs that won't be
+//inherited as given, and ACLs the next chmod will discard or reduce. No
+//warnings means ZFS keeps and inherits acl as it is
+func (p *ZFSProperties) Warnings(acl *ACL) []string {
+	var warnings []string
+	if !acl.IsTrivial() {
+		switch p.ACLMode {
+		case ZFS_ACLMODE_DISCARD:
+			warnings = append(warnings, "aclmode=discard: the next chmod replaces the acl with one derived from the mode")
+		case ZFS_ACLMODE_GROUPMASK:
+			warnings = append(warnings, "aclmode=groupmask: the next chmod reduces the permissions of every ace to the group bits")
+		case ZFS_ACLMODE_RESTRICTED:
+			warnings = append(warnings, "aclmode=restricted: chmod will fail on this file while the acl is non-trivial")
+		}
+	}
+
+	for _, ace := range acl.aceList {
+		if ace.Flags&(NFS4_ACE_FILE_INHERIT_ACE|NFS4_ACE_DIRECTORY_INHERIT_ACE) == 0 {
+			continue
+		}
+		switch p.ACLInherit {
+		case ZFS_ACLINHERIT_DISCARD:
+			warnings = append(warnings, fmt.Sprintf("aclinherit=discard: %s won't be inherited", ace.Format(false, acl.isDirectory)))
+		case ZFS_ACLINHERIT_NOALLOW:
+			if ace.AceType == NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE {
+				warnings = append(warnings, fmt.Sprintf("aclinherit=noallow: %s won't be inherited", ace.Format(false, acl.isDirectory)))
+			}
+		case ZFS_ACLINHERIT_PASSTHROUGH, ZFS_ACLINHERIT_PASSTHROUGH_X:
+		default:
+			if ace.AccessMask&(NFS4_ACE_WRITE_ACL|NFS4_ACE_WRITE_OWNER) != 0 {
+				warnings = append(warnings, fmt.Sprintf("aclinherit=restricted: %s is inherited without write_acl and write_owner", ace.Format(false, acl.isDirectory)))
+			}
+		}
+	}
+
+	return warnings
+}
+
+//Checks before a write what the dataset holding path will do to acl, as
+//ZFSProperties.Warnings, logging each warning
+func CheckZFS(path string, acl *ACL) ([]string, error) {
+	props, err := ZFSPropertiesForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := props.Warnings(acl)
+	for _, warning := range warnings {
+		currentLogger().Warnf("nfs4acl: %s: %s", path, warning)
+	}
+	return warnings, nil
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+// Package nfs4acltest provides fixtures for testing code built on
+// libnfs4acl-go: ACLs and packed xattrs from readable specs, comparisons
+// reporting readable diffs, and random valid ACLs
+
+package nfs4acltest
+
+import (
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"math/rand"
+	"testing"
+)
+
+//Most ACEs RandomACL puts in an ACL
+const MAX_RANDOM_ACES = 8
+
+//Parses spec, comma separated ACEs as nfs4_setfacl takes them, e.g.
+//"A::OWNER@:rwatTnNcCy,A:g:GROUP@:rtncy", into an ACL. Fails the test on a
+//bad spec
+func ACL(t testing.TB, spec string, isDir bool) *nfs4acl.ACL {
+	t.Helper()
+	aces, err := nfs4acl.ParseACLSpec(spec, isDir)
+	if err != nil {
+		t.Fatalf("nfs4acltest: bad spec %q: %v", spec, err)
+	}
+	return nfs4acl.NewACL(isDir, aces...)
+}
+
+//Packs the ACL of spec, see ACL, into the system.nfs4_acl xattr value, as
+//read from disk and decoded by XAttrLoad. Fails the test if it can't be packed
+func XAttr(t testing.TB, spec string, isDir bool) []byte {
+	t.Helper()
+	xattr, err := ACL(t, spec, isDir).PackXAttr()
+	if err != nil {
+		t.Fatalf("nfs4acltest: can't pack %q: %v", spec, err)
+	}
+	return xattr
+}
+
+//Marks the test failed unless got has the ACEs of want, in the same order,
+//and is for the same kind of file. The failure lists the ACEs that differ,
+//'-' for missing from got and '+' for unexpected. Reports whether they were
+//equal
+func AssertEqual(t testing.TB, got, want *nfs4acl.ACL) bool {
+	t.Helper()
+	if got == nil || want == nil {
+		if got != want {
+			t.Errorf("acls differ: got %v, want %v", got, want)
+			return false
+		}
+		return true
+	}
+
+	if got.IsDirectory() != want.IsDirectory() {
+		t.Errorf("acls differ: got a directory acl %t, want %t", got.IsDirectory(), want.IsDirectory())
+		return false
+	}
+	if diff := nfs4acl.DiffACLs(want, got); diff.Changed() {
+		t.Errorf("acls differ:\n%s", diff)
+		return false
+	}
+	return true
+}
+
+//Whos RandomACL picks from, besides numbered users and groups
+var randomWhos = []string{
+	nfs4acl.NFS4_ACL_WHO_OWNER_STRING,
+	nfs4acl.NFS4_ACL_WHO_GROUP_STRING,
+	nfs4acl.NFS4_ACL_WHO_EVERYONE_STRING,
+	nfs4acl.NFS4_ACL_WHO_AUTHENTICATED_STRING,
+}
+
+//Access mask bits RandomACL picks from
+var randomMaskBits = []nfs4acl.AccessMask{
+	nfs4acl.NFS4_ACE_READ_DATA,
+	nfs4acl.NFS4_ACE_WRITE_DATA,
+	nfs4acl.NFS4_ACE_APPEND_DATA,
+	nfs4acl.NFS4_ACE_READ_NAMED_ATTRS,
+	nfs4acl.NFS4_ACE_WRITE_NAMED_ATTRS,
+	nfs4acl.NFS4_ACE_EXECUTE,
+	nfs4acl.NFS4_ACE_DELETE_CHILD,
+	nfs4acl.NFS4_ACE_READ_ATTRIBUTES,
+	nfs4acl.NFS4_ACE_WRITE_ATTRIBUTES,
+	nfs4acl.NFS4_ACE_DELETE,
+	nfs4acl.NFS4_ACE_READ_ACL,
+	nfs4acl.NFS4_ACE_WRITE_ACL,
+	nfs4acl.NFS4_ACE_WRITE_OWNER,
+	nfs4acl.NFS4_ACE_SYNCHRONIZE,
+}
+
+//Flags RandomACL picks from. IDENTIFIER_GROUP follows from the who
+var randomFlags = []nfs4acl.AceFlags{
+	nfs4acl.NFS4_ACE_FILE_INHERIT_ACE,
+	nfs4acl.NFS4_ACE_DIRECTORY_INHERIT_ACE,
+	nfs4acl.NFS4_ACE_NO_PROPAGATE_INHERIT_ACE,
+	nfs4acl.NFS4_ACE_INHERIT_ONLY_ACE,
+	nfs4acl.NFS4_ACE_SUCCESSFUL_ACCESS_ACE_FLAG,
+	nfs4acl.NFS4_ACE_FAILED_ACCESS_ACE_FLAG,
+	nfs4acl.NFS4_ACE_INHERITED_ACE,
+}
+
+//Generates an ACL of up to MAX_RANDOM_ACES ACEs with random types, whos,
+//flags and masks, for fuzzing and property tests. The ACL passes
+//ValidateFlags and packs, and the same r state always gives the same ACL
+func RandomACL(r *rand.Rand, isDir bool) *nfs4acl.ACL {
+	acl := nfs4acl.NewACL(isDir)
+	for n := r.Intn(MAX_RANDOM_ACES + 1); n > 0; n-- {
+		acl.AppendACE(randomACE(r))
+	}
+	//drops or repairs the flag combinations that make no sense
+	acl.FixFlags()
+	return acl
+}
+
+func randomACE(r *rand.Rand) *nfs4acl.ACE {
+	//mostly allow and deny, as in real ACLs
+	aceType := nfs4acl.NFS4_ACE_ACCESS_ALLOWED_ACE_TYPE
+	switch roll := r.Intn(10); {
+	case roll >= 9:
+		aceType = nfs4acl.NFS4_ACE_SYSTEM_AUDIT_ACE_TYPE
+		if r.Intn(2) == 0 {
+			aceType = nfs4acl.NFS4_ACE_SYSTEM_ALARM_ACE_TYPE
+		}
+	case roll >= 6:
+		aceType = nfs4acl.NFS4_ACE_ACCESS_DENIED_ACE_TYPE
+	}
+
+	var flags nfs4acl.AceFlags
+	for _, flag := range randomFlags {
+		if r.Intn(4) == 0 {
+			flags |= flag
+		}
+	}
+
+	var mask nfs4acl.AccessMask
+	for mask == 0 {
+		for _, bit := range randomMaskBits {
+			if r.Intn(2) == 0 {
+				mask |= bit
+			}
+		}
+	}
+
+	var who string
+	switch roll := r.Intn(3); {
+	case roll == 0:
+		who = randomWhos[r.Intn(len(randomWhos))]
+	case roll == 1:
+		who = fmt.Sprintf("user%d@example.com", r.Intn(10))
+	default:
+		who = fmt.Sprintf("group%d@example.com", r.Intn(10))
+		flags |= nfs4acl.NFS4_ACE_IDENTIFIER_GROUP
+	}
+	if who == nfs4acl.NFS4_ACL_WHO_GROUP_STRING {
+		flags |= nfs4acl.NFS4_ACE_IDENTIFIER_GROUP
+	}
+
+	return nfs4acl.NewACE(aceType, flags, mask, who)
+}
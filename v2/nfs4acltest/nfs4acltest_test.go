@@ -0,0 +1,102 @@
+// Copyright (c) 2017 Cory Close. See LICENSE file.
+
+package nfs4acltest
+
+import (
+	"fmt"
+	"github.com/cclose/libnfs4acl-go/v2"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+//Stands in for the testing.TB handed to the helpers, recording failures
+//instead of failing the test running them
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func TestRandomACLRoundTrips(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		isDir := i%2 == 0
+		acl := RandomACL(r, isDir)
+		if err := acl.ValidateFlags(); err != nil {
+			t.Fatalf("acl %d: %v\n%s", i, err, acl.FormatACL(nfs4acl.PrintOptions{}))
+		}
+
+		xattr, err := acl.PackXAttr()
+		if err != nil {
+			t.Fatalf("acl %d: pack: %v", i, err)
+		}
+		loaded, err := nfs4acl.XAttrLoad(xattr, isDir)
+		if err != nil {
+			t.Fatalf("acl %d: load: %v", i, err)
+		}
+		if !AssertEqual(t, loaded, acl) {
+			t.Fatalf("acl %d doesn't survive packing", i)
+		}
+	}
+}
+
+func TestRandomACLIsDeterministic(t *testing.T) {
+	a := RandomACL(rand.New(rand.NewSource(42)), true)
+	b := RandomACL(rand.New(rand.NewSource(42)), true)
+	AssertEqual(t, a, b)
+}
+
+func TestAssertEqual(t *testing.T) {
+	want := ACL(t, "A::OWNER@:rwatTnNcCy,A:g:GROUP@:rtncy", false)
+
+	rec := &recorder{TB: t}
+	if !AssertEqual(rec, ACL(t, "A::OWNER@:rwatTnNcCy,A:g:GROUP@:rtncy", false), want) || len(rec.failures) != 0 {
+		t.Errorf("equal acls reported as different: %v", rec.failures)
+	}
+
+	rec = &recorder{TB: t}
+	if AssertEqual(rec, ACL(t, "A::OWNER@:rwatTnNcCy,A::EVERYONE@:rtncy", false), want) {
+		t.Error("different acls reported as equal")
+	}
+	if len(rec.failures) != 1 || !strings.Contains(rec.failures[0], "+ A::EVERYONE@") ||
+		!strings.Contains(rec.failures[0], "- A:g:GROUP@") {
+		t.Errorf("failure doesn't list the differing aces: %q", rec.failures)
+	}
+
+	rec = &recorder{TB: t}
+	if AssertEqual(rec, ACL(t, "A::OWNER@:rwatTnNcCy,A:g:GROUP@:rtncy", true), want) {
+		t.Error("directory and file acls reported as equal")
+	}
+
+	rec = &recorder{TB: t}
+	if AssertEqual(rec, nil, want) {
+		t.Error("nil acl reported as equal")
+	}
+}
+
+func TestXAttr(t *testing.T) {
+	spec := "A::OWNER@:rwatTnNcCy,D::EVERYONE@:w"
+	acl, err := nfs4acl.XAttrLoad(XAttr(t, spec, false), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEqual(t, acl, ACL(t, spec, false))
+}
+
+func TestBadSpecFails(t *testing.T) {
+	rec := &recorder{TB: t}
+	ACL(rec, "A::OWNER@", false)
+	if len(rec.failures) == 0 {
+		t.Error("bad spec accepted")
+	}
+}